@@ -0,0 +1,196 @@
+package beclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// NewClient 创建一个可复用的客户端
+// @Desc 返回的客户端协程安全，可在多个协程间共享，每次请求通过R()创建
+// @params baseURL              string  基础访问地址
+// @params disabledBaseURLParse ...bool 是否禁用基础地址解析
+// @return                      *Client 可复用客户端指针
+func NewClient(baseURL string, disabledBaseURLParse ...bool) *Client {
+	// 新建客户端
+	c := new(Client)
+	// 初始化query参数
+	c.querys = make(url.Values)
+	// 初始化默认超时时间为15秒
+	c.timeOut = time.Second * 15
+	// 初始化默认资源类型
+	c.contentType = ContentTypeJson
+	// 初始化共享的HTTP客户端
+	c.httpClient = &http.Client{
+		Transport:     http.DefaultClient.Transport,
+		CheckRedirect: http.DefaultClient.CheckRedirect, // 检查重定向
+		Jar:           http.DefaultClient.Jar,
+	}
+	// 是否禁用地址解析
+	if len(disabledBaseURLParse) > 0 && disabledBaseURLParse[0] {
+		c.disabledBaseURLParse = true
+		c.baseURL = baseURL
+		return c
+	}
+	// 解析基本网址
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		c.errMsg = err
+		return c
+	}
+	// 截取协议，域名或IP，端口号三部分
+	c.baseURL = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	// 赋值基础路由
+	c.pathURL = u.Path
+	// 处理URL参数
+	for key := range u.Query() {
+		c.querys.Set(key, u.Query().Get(key))
+	}
+	// 返回创建的客户端
+	return c
+}
+
+// R 创建一个新的请求
+// @Desc 新请求会拷贝客户端的全部默认配置，修改请求不会影响客户端
+// @return *BeClient 请求控制器指针
+func (c *Client) R() *BeClient {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	// 新建请求
+	r := new(BeClient)
+	r.baseURL = c.baseURL
+	r.disabledBaseURLParse = c.disabledBaseURLParse
+	r.pathURL = c.pathURL
+	r.contentType = c.contentType
+	r.timeOut = c.timeOut
+	r.httpClient = c.httpClient
+	r.debug = c.debug
+	r.errMsg = c.errMsg
+	// 拷贝中间件
+	r.middlewares = append([]Middleware(nil), c.middlewares...)
+	r.rateLimiter = c.rateLimiter
+	// 拷贝编解码器
+	r.codecs = make(map[string]Codec, len(c.codecs))
+	for key, codec := range c.codecs {
+		r.codecs[key] = codec
+	}
+	// 拷贝query参数
+	r.querys = make(url.Values, len(c.querys))
+	for key, vals := range c.querys {
+		r.querys[key] = append([]string(nil), vals...)
+	}
+	// 拷贝请求头及Cookie
+	c.headers.Range(func(key, val interface{}) bool {
+		r.headers.Store(key, val)
+		return true
+	})
+	c.cookies.Range(func(key, val interface{}) bool {
+		r.cookies.Store(key, val)
+		return true
+	})
+	// 初始化默认下载缓冲区
+	r.DownloadBufferSize(1024 * 1024 * 5)
+	// 配置多线程下载参数
+	r.DownloadMultiThread(20, 1024*1024*100)
+	// 返回创建的请求
+	return r
+}
+
+// Header 配置默认请求头
+// @Desc 多次调用相同KEY的值会被覆盖
+// @params key string  请求头名称
+// @params val string  请求同内容
+// @return     *Client 可复用客户端指针
+func (c *Client) Header(key, val string) *Client {
+	c.headers.Store(key, val)
+	return c
+}
+
+// Cookie 配置默认请求Cookie
+// @Desc 多次调用相同KEY的值会被覆盖
+// @params key string  Cookie名称
+// @params val string  Cookie内容
+// @return     *Client 可复用客户端指针
+func (c *Client) Cookie(key, val string) *Client {
+	c.cookies.Store(key, val)
+	return c
+}
+
+// Query 配置默认请求URL后参数
+// @Desc 多次调用相同KEY的值会被覆盖
+// @params key string  参数名称
+// @params val string  参数内容
+// @return     *Client 可复用客户端指针
+func (c *Client) Query(key, val string) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.querys.Set(key, val)
+	return c
+}
+
+// ContentType 配置默认资源类型
+// @Desc 请求体将会被格式化为该资源类型
+// @return *Client 可复用客户端指针
+func (c *Client) ContentType(contentType ContentTypeType) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.contentType = contentType
+	return c
+}
+
+// Debug 开启默认Debug模式
+// @Desc 打印的是JSON格式化后的数据
+// @return *Client 可复用客户端指针
+func (c *Client) Debug() *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.debug = true
+	return c
+}
+
+// TimeOut 配置默认请求及响应的超时时间
+// @params timeOut time.Duration 请求超时时间
+// @return         *Client       可复用客户端指针
+func (c *Client) TimeOut(timeOut time.Duration) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeOut = timeOut
+	return c
+}
+
+// Transport 配置共享的传输层
+// @Desc 全部请求共用该传输层的连接池，为nil时使用http.DefaultTransport
+// @params transport http.RoundTripper 传输层
+// @return           *Client           可复用客户端指针
+func (c *Client) Transport(transport http.RoundTripper) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// 替换为新的HTTP客户端，已创建的请求不受影响
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+	return c
+}
+
+// HttpClient 配置共享的HTTP客户端
+// @Desc 该客户端的Timeout会被请求的超时时间覆盖（仅作用于每次请求的浅拷贝）
+// @params client *http.Client HTTP客户端
+// @return        *Client      可复用客户端指针
+func (c *Client) HttpClient(client *http.Client) *Client {
+	if client == nil {
+		return c
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.httpClient = client
+	return c
+}
+
+// GetHttpClient 获取共享的HTTP客户端
+// @return *http.Client HTTP客户端
+func (c *Client) GetHttpClient() *http.Client {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.httpClient
+}
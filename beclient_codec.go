@@ -0,0 +1,187 @@
+package beclient
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/ajg/form"
+)
+
+// codecRegistry 全局注册的编解码器（资源类型 => Codec）
+var codecRegistry sync.Map
+
+// init 注册内置的编解码器
+func init() {
+	RegisterCodec(JSONCodec{})
+	RegisterCodec(XMLCodec{})
+	RegisterCodec(FormCodec{})
+}
+
+// RegisterCodec 全局注册编解码器
+// @Desc 已注册的资源类型会被覆盖，对全部客户端生效（客户端注册的编解码器优先）
+// @params codec Codec 编解码器
+func RegisterCodec(codec Codec) {
+	for _, contentType := range codec.ContentTypes() {
+		codecRegistry.Store(mediaType(contentType), codec)
+	}
+}
+
+// Codec 注册客户端编解码器
+// @Desc 仅对通过R()创建的请求生效，优先于全局注册的编解码器
+// @params codec Codec   编解码器
+// @return      *Client 可复用客户端指针
+func (c *Client) Codec(codec Codec) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.codecs == nil {
+		c.codecs = make(map[string]Codec)
+	}
+	for _, contentType := range codec.ContentTypes() {
+		c.codecs[mediaType(contentType)] = codec
+	}
+	return c
+}
+
+// Codec 注册请求编解码器
+// @Desc 仅对本次请求生效，优先于客户端及全局注册的编解码器
+// @params codec Codec     编解码器
+// @return      *BeClient 客户端指针
+func (c *BeClient) Codec(codec Codec) *BeClient {
+	if c.codecs == nil {
+		c.codecs = make(map[string]Codec)
+	}
+	for _, contentType := range codec.ContentTypes() {
+		c.codecs[mediaType(contentType)] = codec
+	}
+	return c
+}
+
+// codec 查找资源类型对应的编解码器
+// @params contentType ContentTypeType 资源类型
+// @return             Codec           编解码器
+// @return             bool            是否找到
+func (c *BeClient) codec(contentType ContentTypeType) (Codec, bool) {
+	key := mediaType(contentType)
+	if codec, ok := c.codecs[key]; ok {
+		return codec, true
+	}
+	if codec, ok := codecRegistry.Load(key); ok {
+		return codec.(Codec), true
+	}
+	return nil, false
+}
+
+// responseCodec 根据响应头的Content-Type查找编解码器
+// @Desc 没有对应的编解码器时，按结构化后缀（例如application/problem+json）查找JSON或XML编解码器
+// @params contentType string 响应头的Content-Type
+// @return             Codec  编解码器
+// @return             bool   是否找到
+func (c *BeClient) responseCodec(contentType string) (Codec, bool) {
+	if len(contentType) == 0 {
+		return nil, false
+	}
+	key := mediaType(ContentTypeType(contentType))
+	if codec, ok := c.codec(ContentTypeType(key)); ok {
+		return codec, true
+	}
+	// 结构化后缀
+	switch {
+	case strings.HasSuffix(key, "+json"):
+		return c.codec(ContentTypeJson)
+	case strings.HasSuffix(key, "+xml"):
+		return c.codec(ContentTypeAppXml)
+	}
+	return nil, false
+}
+
+// rawConvertData 将原始响应内容赋值到接收原始内容的变量
+// @Desc 支持*[]byte、*string及io.Writer（例如*bytes.Buffer，内容会追加写入）
+// @params src []byte      响应内容
+// @params dst interface{} 用户自定义变量地址
+// @return     bool        是否为接收原始内容的变量
+// @return     error       错误信息
+func rawConvertData(src []byte, dst interface{}) (bool, error) {
+	switch val := dst.(type) {
+	case *[]byte:
+		*val = src
+	case *string:
+		*val = string(src)
+	case io.Writer:
+		_, err := val.Write(src)
+		return true, err
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// mediaType 获取资源类型的媒体类型部分
+// @Desc 忽略参数部分并转换为小写，例如application/json; charset=utf-8 => application/json
+// @params contentType ContentTypeType 资源类型
+// @return             string          媒体类型
+func mediaType(contentType ContentTypeType) string {
+	if val, _, err := mime.ParseMediaType(string(contentType)); err == nil {
+		return val
+	}
+	return strings.ToLower(strings.TrimSpace(string(contentType)))
+}
+
+// JSONCodec JSON编解码器
+type JSONCodec struct{}
+
+// ContentTypes 支持的资源类型
+func (JSONCodec) ContentTypes() []ContentTypeType {
+	return []ContentTypeType{ContentTypeJson}
+}
+
+// Marshal 编码
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal 解码
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// XMLCodec XML编解码器
+type XMLCodec struct{}
+
+// ContentTypes 支持的资源类型
+func (XMLCodec) ContentTypes() []ContentTypeType {
+	return []ContentTypeType{ContentTypeTextXml, ContentTypeAppXml}
+}
+
+// Marshal 编码
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+// Unmarshal 解码
+func (XMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// FormCodec 表单编解码器
+// @Desc 请求为multipart/form-data时由multipart编码，不经过该编解码器
+type FormCodec struct{}
+
+// ContentTypes 支持的资源类型
+func (FormCodec) ContentTypes() []ContentTypeType {
+	return []ContentTypeType{ContentTypeFormURL, ContentTypeFormBody}
+}
+
+// Marshal 编码
+func (FormCodec) Marshal(v interface{}) ([]byte, error) {
+	val, err := form.EncodeToString(v)
+	return []byte(val), err
+}
+
+// Unmarshal 解码
+func (FormCodec) Unmarshal(data []byte, v interface{}) error {
+	return form.DecodeString(v, string(data))
+}
@@ -1,12 +1,44 @@
 package beclient
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"sync"
 	"time"
 )
 
+// errNotRewindable 请求Body无法重建
+var errNotRewindable = errors.New("request body cannot be rewound for retry")
+
+// errRangeNotSupported 服务端未正确响应Range请求
+var errRangeNotSupported = errors.New("server did not honour the requested range")
+
+// ErrResourceChanged 下载过程中远程资源发生了变化（If-Range校验失败）
+var ErrResourceChanged = errors.New("remote resource has changed during download")
+
+// ErrDownloadCanceled 下载任务已被取消
+var ErrDownloadCanceled = errors.New("download job canceled")
+
+// ErrInsufficientSpace 磁盘可用空间不足以保存下载内容
+var ErrInsufficientSpace = errors.New("insufficient disk space for download")
+
+// ErrStopIteration 逐个解码回调返回该错误时停止解码，且不作为错误返回
+var ErrStopIteration = errors.New("stop iteration")
+
+// Codec 编解码器
+// @Desc 根据资源类型格式化请求参数及转换响应内容，可通过RegisterCodec全局注册或通过Client.Codec、BeClient.Codec按客户端注册
+type Codec interface {
+	// ContentTypes 支持的资源类型（忽略参数部分，例如charset）
+	ContentTypes() []ContentTypeType
+	// Marshal 将请求参数编码为请求体
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 将响应内容解码到用户自定义变量
+	Unmarshal(data []byte, v interface{}) error
+}
+
 // MethodType 请求类型
 type MethodType string
 
@@ -41,36 +73,314 @@ const (
 	ContentTypeJson ContentTypeType = "application/json"
 	// ContentTypeFormURL form表单数据被编码为key/value格式拼接到URL后
 	ContentTypeFormURL ContentTypeType = "application/x-www-form-urlencoded"
-	// ContentTypeFormBody 需要在表单中进行文件上传时，就需要使用该格式
+	// ContentTypeFormBody 需要在表单中进行文件上传时，就需要使用该格式（请求体将被编码为multipart/form-data）
 	ContentTypeFormBody ContentTypeType = "multipart/form-data"
+	// ContentTypeAuto 根据响应头的Content-Type转换响应内容（仅用于响应）
+	ContentTypeAuto ContentTypeType = "auto"
+)
+
+// HashAlgo 哈希算法类型
+type HashAlgo string
+
+const (
+	// HashMD5 MD5算法
+	HashMD5 HashAlgo = "md5"
+	// HashSHA1 SHA-1算法
+	HashSHA1 HashAlgo = "sha1"
+	// HashSHA256 SHA-256算法
+	HashSHA256 HashAlgo = "sha256"
+	// HashSHA512 SHA-512算法
+	HashSHA512 HashAlgo = "sha512"
 )
 
 // DownloadCallbackFuncType 下载内容回调方法类型
+// @Desc 执行时机请参考Download
 // @params currSize  int64 当前已下载大小
 // @params totalSize int64 资源内容总大小
 type DownloadCallbackFuncType func(currSize, totalSize float64)
 
-// BeClient 客户端控制器
+// UploadCallbackFuncType 上传内容回调方法类型
+// @params currSize  int64 当前已上传大小
+// @params totalSize int64 请求体总大小（未知时为-1）
+type UploadCallbackFuncType func(currSize, totalSize float64)
+
+// SSEEvent 服务端推送事件（text/event-stream）
+type SSEEvent struct {
+	ID    string        // 事件ID（未指定时为最近一次收到的ID）
+	Event string        // 事件类型（未指定时为message）
+	Data  string        // 事件数据（多行data以\n连接）
+	Retry time.Duration // 服务端指定的重连间隔（未指定时为0）
+}
+
+// SSEHandlerFuncType 服务端推送事件处理方法类型
+// @Desc 返回ErrStopIteration时停止接收且不返回错误，返回其他错误时停止接收并返回该错误
+// @params event *SSEEvent 服务端推送事件
+// @return       error     错误信息
+type SSEHandlerFuncType func(event *SSEEvent) error
+
+// RoundTripFunc 发送HTTP请求的方法类型
+type RoundTripFunc func(request *http.Request) (*http.Response, error)
+
+// Middleware 请求中间件
+// @Desc 包装next以在请求发出前修改请求体，或在响应返回后检查、替换响应体
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// RetryPolicy 自动重试策略
+type RetryPolicy struct {
+	MaxAttempts        int           // 最大尝试次数（包括首次请求，小于等于1时不重试）
+	MinBackoff         time.Duration // 首次重试前的等待时间（默认100毫秒），之后按指数增长并附加随机抖动
+	MaxBackoff         time.Duration // 最大等待时间（默认10秒）
+	StatusCodes        []int         // 需要重试的响应状态码（默认429、502、503、504）
+	RetryNonIdempotent bool          // 是否允许重试非幂等请求（POST、PATCH）
+}
+
+// RateLimiter 令牌桶限速器
+// @Desc 协程安全，同一个限速器可由多个请求及客户端共享，共享时总带宽受同一限制
+type RateLimiter struct {
+	mutex  sync.Mutex // 令牌桶锁
+	rate   float64    // 每秒生成的令牌数（byte/s，小于等于0时不限速）
+	burst  float64    // 令牌桶容量
+	tokens float64    // 当前令牌数（可为负数，表示已预支的令牌）
+	last   time.Time  // 上一次生成令牌的时间
+}
+
+// StatusError 响应状态错误
+// @Desc 非2xx响应（开启StatusCheck时）及下载失败的响应会返回该错误，可通过errors.As获取
+type StatusError struct {
+	StatusCode int         // 响应状态码
+	Status     string      // 响应状态
+	Header     http.Header // 响应头
+	Body       []byte      // 原始响应内容
+}
+
+// ChecksumError 下载内容校验失败错误
+// @Desc 校验失败时已下载的文件会被删除，可通过errors.As获取
+type ChecksumError struct {
+	Algo     HashAlgo // 哈希算法
+	Source   string   // 校验值来源（DownloadVerify或响应头名称）
+	Expected string   // 期望的十六进制哈希值
+	Actual   string   // 实际的十六进制哈希值
+}
+
+// Client 可复用的客户端
+// @Desc 保存基础地址、默认请求头、默认Cookie、超时时间及共享的HTTP客户端，协程安全，
+// 通过R()创建的每一个请求都会拷贝这些默认配置
+type Client struct {
+	mutex                sync.RWMutex     // 配置读写锁
+	baseURL              string           // 基本网址Host Name
+	disabledBaseURLParse bool             // 是否禁用基础地址解析
+	pathURL              string           // 基础路由地址
+	contentType          ContentTypeType  // 默认资源类型
+	codecs               map[string]Codec // 客户端注册的编解码器
+	headers              sync.Map         // 默认请求头
+	cookies              sync.Map         // 默认请求Cookie
+	querys               url.Values       // 默认路由地址后的追加参数
+	timeOut              time.Duration    // 默认请求及响应的超时时间
+	httpClient           *http.Client     // 共享的HTTP客户端（连接池随Transport复用）
+	middlewares          []Middleware     // 客户端中间件
+	rateLimiter          *RateLimiter     // 客户端共享的限速器
+	debug                bool             // 是否Debug输出
+	errMsg               error            // 错误信息
+}
+
+// HLSVariant HLS清晰度（主播放列表中的#EXT-X-STREAM-INF）
+type HLSVariant struct {
+	Bandwidth  int64  // 带宽（bit/s）
+	Resolution string // 分辨率（例如：1280x720）
+	Width      int64  // 宽度
+	Height     int64  // 高度
+	Codecs     string // 编码格式
+	URI        string // 媒体播放列表地址
+}
+
+// HLSVariantSelectFuncType HLS清晰度选择函数类型
+// @params variants []*HLSVariant 主播放列表中的全部清晰度
+// @return          *HLSVariant   选中的清晰度（返回nil时下载失败）
+type HLSVariantSelectFuncType func(variants []*HLSVariant) *HLSVariant
+
+// DownloadEventType 下载事件类型
+type DownloadEventType string
+
+const (
+	// DownloadEventStart 开始下载
+	DownloadEventStart DownloadEventType = "start"
+	// DownloadEventProgress 定时的进度事件
+	DownloadEventProgress DownloadEventType = "progress"
+	// DownloadEventSegmentComplete 分段下载完成
+	DownloadEventSegmentComplete DownloadEventType = "segment_complete"
+	// DownloadEventRetry 分段下载失败，等待重试
+	DownloadEventRetry DownloadEventType = "retry"
+	// DownloadEventFallback 多线程下载回退到单线程下载
+	DownloadEventFallback DownloadEventType = "fallback"
+	// DownloadEventFinish 下载结束（Err不为nil时表示下载失败）
+	DownloadEventFinish DownloadEventType = "finish"
+)
+
+// DownloadSegmentProgress 分段下载进度
+type DownloadSegmentProgress struct {
+	Index int    // 分段序号
+	Start int64  // 分段起始偏移量（闭区间）
+	End   int64  // 分段结束偏移量（闭区间，资源大小未知时小于0）
+	Done  int64  // 分段已下载量
+	State string // 分段状态（pending、active、retrying、finished）
+}
+
+// DownloadProgress 下载进度事件
+type DownloadProgress struct {
+	Event            DownloadEventType         // 事件类型
+	DoneSize         int64                     // 已下载量
+	TotalSize        int64                     // 资源总大小（未知时为-1）
+	Speed            float64                   // 瞬时速度（byte/s，仅进度事件）
+	AverageSpeed     float64                   // 平均速度（byte/s，不包括续传前已下载的部分）
+	Elapsed          time.Duration             // 已用时间
+	ETA              time.Duration             // 预计剩余时间（未知时为-1）
+	ActiveSegments   int                       // 下载中的分段数量
+	FinishedSegments int                       // 已完成的分段数量
+	RetryingSegments int                       // 等待重试的分段数量
+	Segments         []DownloadSegmentProgress // 全部分段的进度
+	Segment          *DownloadSegmentProgress  // 事件相关的分段（分段完成及重试事件）
+	Err              error                     // 事件相关的错误（重试、回退及结束事件）
+}
+
+// DownloadProgressFuncType 下载进度事件回调方法类型
+type DownloadProgressFuncType func(progress *DownloadProgress)
+
+// SyncModeType 下载文件同步到磁盘的方式
+type SyncModeType string
+
+const (
+	// SyncNone 不主动同步，由操作系统决定写入时机
+	SyncNone SyncModeType = "none"
+	// SyncEnd 下载完成时同步一次（默认）
+	SyncEnd SyncModeType = "end"
+	// SyncPeriodic 下载过程中定时同步，完成时再同步一次
+	SyncPeriodic SyncModeType = "periodic"
+)
+
+// ConflictPolicyType 下载文件已存在时的处理方式
+type ConflictPolicyType string
+
+const (
+	// ConflictOverwrite 覆盖已存在的文件（默认）
+	ConflictOverwrite ConflictPolicyType = "overwrite"
+	// ConflictSkip 跳过下载
+	ConflictSkip ConflictPolicyType = "skip"
+	// ConflictRename 自动重命名为name (1).ext
+	ConflictRename ConflictPolicyType = "rename"
+)
+
+// DownloadStat 远程资源信息
+type DownloadStat struct {
+	Size         int64       // 资源总大小（未知时为-1）
+	ETag         string      // 资源ETag
+	LastModified string      // 资源最后修改时间
+	ContentType  string      // 资源类型
+	URL          string      // 重定向后的最终地址
+	FileName     string      // 根据Content-Disposition、最终地址及资源类型推断的文件名
+	AcceptRanges bool        // 是否支持Range请求
+	Header       http.Header // 探测响应的响应头
+}
+
+// DownloadJobStatus 下载任务状态
+type DownloadJobStatus string
+
+const (
+	// DownloadJobQueued 排队中
+	DownloadJobQueued DownloadJobStatus = "queued"
+	// DownloadJobRunning 下载中
+	DownloadJobRunning DownloadJobStatus = "running"
+	// DownloadJobPaused 已暂停
+	DownloadJobPaused DownloadJobStatus = "paused"
+	// DownloadJobCompleted 已完成
+	DownloadJobCompleted DownloadJobStatus = "completed"
+	// DownloadJobFailed 下载失败
+	DownloadJobFailed DownloadJobStatus = "failed"
+	// DownloadJobCanceled 已取消
+	DownloadJobCanceled DownloadJobStatus = "canceled"
+)
+
+// DownloadManager 下载管理器
+// @Desc 协程安全，按并发数量及带宽限制执行排队的下载任务
+type DownloadManager struct {
+	mutex       sync.Mutex                 // 管理器锁
+	concurrency int                        // 同时下载的任务数量
+	limiter     *RateLimiter               // 全部任务共享的限速器
+	newRequest  func(url string) *BeClient // 创建下载请求的方法
+	queuePath   string                     // 队列持久化文件路径
+	jobs        []*DownloadJob             // 全部任务（按添加顺序）
+	running     int                        // 下载中的任务数量
+	stopped     bool                       // 是否已停止
+	wg          sync.WaitGroup             // 等待下载中的任务结束
+}
+
+// DownloadJob 下载任务
+// @Desc 由DownloadManager创建，状态由管理器的锁保护
+type DownloadJob struct {
+	manager  *DownloadManager   // 所属管理器
+	url      string             // 下载地址
+	savePath string             // 保存路径
+	status   DownloadJobStatus  // 任务状态
+	active   bool               // 下载协程是否在运行
+	cancel   context.CancelFunc // 取消本次下载
+	err      error              // 下载结果
+	progress *DownloadProgress  // 最近一次下载进度
+	done     chan struct{}      // 任务结束（完成、失败或取消）时关闭
+}
+
+// BeClient 单次请求控制器
+// @Desc 每次请求都应使用独立的BeClient，可通过New或Client.R()创建
 type BeClient struct {
-	baseURL              string                   // 基本网址Host Name
-	disabledBaseURLParse bool                     // 是否禁用基础地址解析
-	pathURL              string                   // 路由地址
-	contentType          ContentTypeType          // 资源类型（会根据该类型来格式化请求参数，默认值：application/json）
-	headers              sync.Map                 // 请求头
-	cookies              sync.Map                 // 请求Cookie
-	querys               url.Values               // 路由地址后的追加参数
-	method               MethodType               // 请求方法类型
-	data                 interface{}              // 请求参数
-	isDownloadRequest    bool                     // 是否为下载请求
-	downloadBufferSize   int64                    // 下载缓冲区大小（默认1024*5byte，最小5byte，最大1024*1024byte）
-	downloadMaxThread    int64                    // 最大下载线程数量（默认20）
-	downloadMaxSize      int64                    // 单个线程最大下载容量，仅在使用线程数低于最大线程数时有效（默认1024*100byte，最小5byte，最大1024*1024*10byte）
-	downloadSavePath     string                   // 下载资源保存路径
-	downloadCallFunc     DownloadCallbackFuncType // 下载进度回调函数
-	timeOut              time.Duration            // 请求及响应的超时时间
-	client               *http.Client             // HTTP客户端
-	request              *http.Request            // 请求体
-	response             *http.Response           // 响应体
-	debug                bool                     // 是否Debug输出，（输出为json格式化后的数据）
-	errMsg               error                    // 错误信息
+	baseURL                  string                   // 基本网址Host Name
+	disabledBaseURLParse     bool                     // 是否禁用基础地址解析
+	pathURL                  string                   // 路由地址
+	contentType              ContentTypeType          // 资源类型（会根据该类型来格式化请求参数，默认值：application/json）
+	codecs                   map[string]Codec         // 请求注册的编解码器（包括客户端注册的）
+	headers                  sync.Map                 // 请求头
+	cookies                  sync.Map                 // 请求Cookie
+	querys                   url.Values               // 路由地址后的追加参数
+	method                   MethodType               // 请求方法类型
+	data                     interface{}              // 请求参数
+	formParts                []*formPart              // multipart/form-data表单分段
+	formBoundary             string                   // multipart/form-data分隔符
+	uploadCallFunc           UploadCallbackFuncType   // 上传进度回调函数
+	isDownloadRequest        bool                     // 是否为下载请求
+	downloadBufferSize       int64                    // 下载缓冲区大小（默认1024*5byte，最小5byte，最大1024*1024byte）
+	downloadMaxThread        int64                    // 最大下载线程数量（默认20）
+	downloadMaxSize          int64                    // 单个线程最大下载容量，仅在使用线程数低于最大线程数时有效（默认1024*100byte，最小5byte，最大1024*1024*10byte）
+	downloadSavePath         string                   // 下载资源保存路径
+	downloadDir              string                   // 下载文件夹（文件名根据响应推断）
+	downloadConflict         ConflictPolicyType       // 下载文件已存在时的处理方式
+	downloadPreallocate      bool                     // 多线程下载前是否检查磁盘空间并预分配
+	downloadSyncMode         SyncModeType             // 下载文件同步到磁盘的方式
+	downloadSyncInterval     time.Duration            // 定时同步的间隔
+	downloadStat             *DownloadStat            // 探测到的资源信息
+	downloadCallFunc         DownloadCallbackFuncType // 下载进度回调函数
+	downloadProgressFunc     DownloadProgressFuncType // 下载进度事件回调函数
+	downloadProgressInterval time.Duration            // 下载进度事件间隔
+	tracker                  *downloadTracker         // 下载进度跟踪器
+	downloadWriter           io.Writer                // 顺序写入目标（不为nil时不写入文件）
+	downloadWriterAt         io.WriterAt              // 随机写入目标（不为nil时不写入文件）
+	downloadWriterAtSize     int64                    // 随机写入目标的期望资源大小（小于等于0时不校验）
+	downloadResume           bool                     // 是否开启断点续传
+	downloadTempDir          string                   // 下载临时文件夹（默认为保存路径所在的文件夹）
+	downloadKeepPartial      *bool                    // 下载失败时是否保留未完成的文件（默认开启断点续传时保留）
+	downloadChecksums        []*downloadChecksum      // 下载内容的期望校验值
+	downloadMirrors          []*url.URL               // 多线程下载的镜像地址
+	downloadHLS              bool                     // 是否为HLS（m3u8）下载
+	hlsVariantSelector       HLSVariantSelectFuncType // HLS清晰度选择函数（默认选择带宽最高的）
+	hlsKeys                  sync.Map                 // HLS密钥缓存
+	timeOut                  time.Duration            // 请求及响应的超时时间
+	ctx                      context.Context          // 请求上下文（作用于全部请求，包括多线程下载的每个分段）
+	retryPolicy              *RetryPolicy             // 自动重试策略
+	middlewares              []Middleware             // 中间件（客户端中间件在前）
+	rateLimiter              *RateLimiter             // 限速器（上传及下载共用）
+	statusCheck              bool                     // 是否将非2xx响应视为错误
+	errorBody                interface{}              // 非2xx响应内容接收变量
+	errorBodyContentType     []ContentTypeType        // 非2xx响应内容资源类型
+	httpClient               *http.Client             // 共享的HTTP客户端
+	client                   *http.Client             // HTTP客户端（共享HTTP客户端的浅拷贝，携带本次请求的超时时间）
+	request                  *http.Request            // 请求体
+	response                 *http.Response           // 响应体
+	debug                    bool                     // 是否Debug输出，（输出为json格式化后的数据）
+	errMsg                   error                    // 错误信息
 }
@@ -0,0 +1,610 @@
+package beclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// download 下载
+func (c *BeClient) download() (err error) {
+	// 跟踪下载进度
+	c.tracker = c.newDownloadTracker()
+	defer func() { c.tracker.finish(err) }()
+	// HLS分段需要合并到文件，不能写入自定义写入目标
+	if c.downloadHLS && (c.downloadWriter != nil || c.downloadWriterAt != nil) {
+		return errors.New("hls download does not support DownloadTo or DownloadToWriterAt")
+	}
+	// 下载到自定义写入目标
+	if c.downloadWriter != nil {
+		return c.streamDownload(c.downloadWriter)
+	}
+	if c.downloadWriterAt != nil {
+		return c.writerAtDownload()
+	}
+	// 下载到文件
+	return c.downloadFile()
+}
+
+// downloadFile 下载文件
+func (c *BeClient) downloadFile() error {
+	// 是否存在下载地址
+	if len(c.downloadSavePath) == 0 && len(c.downloadDir) == 0 {
+		// 没有保存路径，直接报错
+		return errors.New("download file save path is null")
+	}
+	// 确定保存路径，文件已存在时按冲突处理方式处理
+	skip, err := c.resolveSavePath()
+	if err != nil || skip {
+		return err
+	}
+	// 判断文件夹部分是否为空
+	saveDir := filepath.Dir(c.downloadSavePath)
+	if len(saveDir) == 0 {
+		return errors.New("download file save path dir is nil")
+	}
+	// 创建文件夹部分
+	if err = os.MkdirAll(saveDir, 0755); err != nil {
+		return err
+	}
+	// 创建临时文件夹
+	if len(c.downloadTempDir) > 0 {
+		if err = os.MkdirAll(c.downloadTempDir, 0755); err != nil {
+			return err
+		}
+	}
+	// 下载到临时文件
+	if c.downloadHLS {
+		err = c.hlsDownload()
+	} else {
+		err = c.fetchDownload()
+	}
+	if err != nil {
+		// 清理未完成的下载
+		if !c.keepPartial() {
+			_ = os.Remove(c.downloadTempPath())
+			_ = removeDownloadState(c.downloadStatePath())
+		}
+		return err
+	}
+	// 下载完成后原子替换到保存路径
+	return moveFile(c.downloadTempPath(), c.downloadSavePath)
+}
+
+// fetchDownload 探测资源并选择下载方式
+// @Desc 内容会被写入临时文件
+func (c *BeClient) fetchDownload() error {
+	// 探测是否支持多线程下载
+	stat, ok := c.probeDownload()
+	if !ok {
+		// 直接走单线程下载
+		return c.singleThreadDownload()
+	}
+	// 走多线程下载
+	return c.multiThreadDownload(stat)
+}
+
+// probeDownload 探测资源是否支持多线程下载
+// @return stat *DownloadStat 资源信息（探测失败时为nil）
+// @return ok   bool          是否支持多线程下载
+func (c *BeClient) probeDownload() (stat *DownloadStat, ok bool) {
+	// 复用推断文件名时的探测结果
+	stat = c.downloadStat
+	if stat == nil {
+		var err error
+		if stat, err = c.probeResource(c.context(), c.request.URL); err != nil {
+			return nil, false
+		}
+	}
+	// 需要支持分片下载，并且大小超过缓冲区
+	return stat, stat.AcceptRanges && stat.Size > c.downloadBufferSize
+}
+
+// singleThreadDownload 单线程下载
+// @Desc 开启断点续传时，会携带Range及If-Range从已下载的位置继续下载
+func (c *BeClient) singleThreadDownload() error {
+	// 重建请求（HEAD探测可能已读取请求Body）
+	request, err := rewindRequest(c.request)
+	if err != nil {
+		return err
+	}
+	// 判断是否可以断点续传
+	var offset int64
+	var state *downloadState
+	if c.downloadResume {
+		state = loadDownloadState(c.downloadStatePath())
+	}
+	if state != nil && state.Mode == downloadModeSingle && len(state.validator()) > 0 {
+		if info, err := os.Stat(c.downloadTempPath()); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			request.Header.Set("If-Range", state.validator())
+		}
+	}
+	// 发送请求
+	res, err := c.do(request)
+	if err != nil {
+		return err
+	}
+	// 判断响应是否为空
+	if res == nil {
+		return errors.New("response is nil pointer address")
+	}
+	// 结束时释放
+	defer res.Body.Close()
+	// 赋值response
+	c.response = res
+	// 资源总大小（未知时为-1）
+	totalSize := res.ContentLength
+	// 判断是否请求成功
+	switch {
+	// 续传成功，从已下载的位置继续写入
+	case offset > 0 && res.StatusCode == http.StatusPartialContent:
+		start, _, size, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return fmt.Errorf("unexpected Content-Range %q for resumed download", res.Header.Get("Content-Range"))
+		}
+		totalSize = size
+
+	// 已下载的内容已经完整，与正常完成的下载一样先校验再替换
+	case offset > 0 && res.StatusCode == http.StatusRequestedRangeNotSatisfiable && state.Size == offset:
+		if hasher := c.newDownloadHasher(res.Header, true); hasher != nil {
+			if err = hasher.hashFile(c.downloadTempPath(), offset); err != nil {
+				return err
+			}
+			if err = c.verifyDownload(hasher); err != nil {
+				return err
+			}
+		}
+		return removeDownloadState(c.downloadStatePath())
+
+	// 完整响应，从头开始下载
+	case res.StatusCode == http.StatusOK:
+		offset = 0
+
+	default:
+		// 将返回的错误信息读出
+		errBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		return c.statusError(res, errBody)
+	}
+
+	// 打开文件，边下载边写入，防止内存占用高(非续传时os.O_TRUNC覆盖式写入)
+	flag := os.O_CREATE | os.O_WRONLY
+	if offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(c.downloadTempPath(), flag, 0666)
+	if err != nil {
+		return err
+	}
+	// 延迟关闭文件
+	defer file.Close()
+	// 定位到续传位置
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	// 保存断点续传状态，单线程下载以文件大小作为已下载位置
+	segment := &downloadSegment{Start: 0, End: totalSize - 1, Done: offset}
+	if c.downloadResume {
+		state = newDownloadState(downloadModeSingle, request.URL.String(), totalSize, res.Header, []*downloadSegment{segment})
+		if err = state.save(c.downloadStatePath()); err != nil {
+			return err
+		}
+	}
+
+	// 边下载边计算哈希（续传时先计算已下载的部分）
+	hasher := c.newDownloadHasher(checksumHeader(res), res.StatusCode == http.StatusPartialContent)
+	if hasher != nil && offset > 0 {
+		if err = hasher.hashFile(c.downloadTempPath(), offset); err != nil {
+			return err
+		}
+	}
+
+	// 读取响应直到结束
+	stopSync := c.periodicSync(file)
+	err = c.copyBody(file, res.Body, hasher, segment)
+	stopSync()
+	if err != nil {
+		return err
+	}
+	// 同步到磁盘后关闭文件，再校验内容
+	if err = c.syncFile(file); err != nil {
+		return err
+	}
+	file.Close()
+	if err = c.verifyDownload(hasher); err != nil {
+		return err
+	}
+	// 下载成功，清理断点续传状态
+	if c.downloadResume {
+		return removeDownloadState(c.downloadStatePath())
+	}
+	return nil
+}
+
+// copyBody 读取响应内容并写入
+// @params w       io.Writer        写入目标
+// @params body    io.Reader        响应内容
+// @params hasher  *downloadHasher  哈希计算器（可为nil）
+// @params segment *downloadSegment 下载分段（结束偏移量小于0时表示资源大小未知）
+// @return         error            错误信息
+func (c *BeClient) copyBody(w io.Writer, body io.Reader, hasher *downloadHasher, segment *downloadSegment) error {
+	// 跟踪分段进度（资源大小未知时结束偏移量为-2，总大小为-1）
+	c.tracker.reset(segment.End+1, []*downloadSegment{segment})
+	c.tracker.setSegmentStatus(segment, segmentActive, nil)
+	// 定义下载缓冲区
+	downBuffer := make([]byte, c.downloadBufferSize)
+	// 读取响应直到结束
+	for {
+		// 读取响应体
+		size, readErr := body.Read(downBuffer)
+		// 判断是否发生错误
+		if readErr != nil && readErr != io.EOF {
+			// 有错误，直接返回错误
+			return readErr
+		}
+		// 写入到目标
+		n, err := w.Write(downBuffer[:size])
+		// 判断是否写入正确
+		if err != nil {
+			return err
+		}
+		if n != size {
+			return errors.New("write to file byte length inconsistency")
+		}
+		// 计算哈希
+		if hasher != nil {
+			hasher.Write(downBuffer[:size])
+		}
+		// 追加已下载大小
+		segment.add(int64(size))
+		c.tracker.add(int64(size))
+		// 读取完成
+		if readErr == io.EOF {
+			break
+		}
+	}
+	// 判断是否下载完整
+	if segment.End >= 0 && !segment.finished() {
+		return io.ErrUnexpectedEOF
+	}
+	c.tracker.setSegmentStatus(segment, segmentFinished, nil)
+	return nil
+}
+
+// multiThreadDownload 多线程下载
+// @Desc 开启断点续传时，仅下载状态文件中记录的未完成区域
+func (c *BeClient) multiThreadDownload(stat *DownloadStat) error {
+	// 资源总大小
+	totalSize := stat.Size
+	// 判断是否可以断点续传
+	var state *downloadState
+	if c.downloadResume {
+		state = loadDownloadState(c.downloadStatePath())
+		// 远程资源已发生变化时重新下载
+		if state != nil && (state.Mode != downloadModeMulti || !state.matches(totalSize, stat.Header)) {
+			state = nil
+		}
+		// 文件已被删除时重新下载
+		if _, err := os.Stat(c.downloadTempPath()); err != nil {
+			state = nil
+		}
+	}
+
+	// 打开文件，边下载边写入，防止内存占用高(非续传时os.O_TRUNC覆盖式写入)
+	flag := os.O_CREATE | os.O_WRONLY
+	if state == nil {
+		state = newDownloadState(downloadModeMulti, c.request.URL.String(), totalSize, stat.Header, c.splitSegments(totalSize))
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(c.downloadTempPath(), flag, 0666)
+	if err != nil {
+		return err
+	}
+	// 延迟关闭文件
+	defer file.Close()
+
+	// 检查磁盘空间并预分配
+	if err = c.preallocateFile(file, totalSize); err != nil {
+		return err
+	}
+
+	// 下载未完成的分段
+	stopSync := c.periodicSync(file)
+	err = c.rangeDownload(file, state, c.downloadResume)
+	stopSync()
+	// 同步到磁盘
+	if err == nil {
+		err = c.syncFile(file)
+	}
+	// 下载完成后校验文件内容
+	if err == nil {
+		if hasher := c.newDownloadHasher(stat.Header, false); hasher != nil {
+			file.Close()
+			if err = hasher.hashFile(c.downloadTempPath(), -1); err == nil {
+				err = c.verifyDownload(hasher)
+			}
+		}
+	}
+	// 更新断点续传状态
+	var checksumErr *ChecksumError
+	if c.downloadResume {
+		switch {
+		case errors.As(err, &checksumErr):
+			// 校验失败，文件及状态已被删除
+		case err == nil:
+			// 下载成功，清理状态
+			return removeDownloadState(c.downloadStatePath())
+		case errors.Is(err, ErrResourceChanged), errors.Is(err, errRangeNotSupported):
+			// 远程资源已变化或不支持分段，已下载的内容无效
+			_ = removeDownloadState(c.downloadStatePath())
+		default:
+			// 保存进度以便下次继续
+			_ = c.saveState(file, state)
+		}
+	}
+	// 服务端未正确响应Range时回退到单线程下载，避免写入错误的内容
+	if errors.Is(err, errRangeNotSupported) {
+		file.Close()
+		c.tracker.event(DownloadEventFallback, nil, err)
+		return c.singleThreadDownload()
+	}
+	return err
+}
+
+// rangeDownload 多线程下载未完成的分段
+// @Desc 下载线程从调度器领取分段，空闲线程会拆分其他线程剩余的下载量，每个分段独立重试，任一分段失败时取消全部分段的下载
+// @params w       io.WriterAt    写入目标
+// @params state   *downloadState 下载状态
+// @params persist bool           是否定时保存断点续传状态
+// @return         error          错误信息
+func (c *BeClient) rangeDownload(w io.WriterAt, state *downloadState, persist bool) error {
+	// 初始化可取消上下文（继承请求上下文，超时时间作为额外的截止时间）
+	globalCtx, globalCancel := c.withTimeout(c.context())
+	// 初始化等待组
+	var wg sync.WaitGroup
+	// 首个发生的错误
+	var downloadErr error
+	var errOnce sync.Once
+	// 仅赋值一次response
+	var responseOnce sync.Once
+	// 记录错误并取消全部线程的下载
+	setErr := func(err error) {
+		errOnce.Do(func() { downloadErr = err })
+		globalCancel()
+	}
+
+	// 定时保存断点续传状态
+	var saveWg sync.WaitGroup
+	saveDone := make(chan struct{})
+	if persist {
+		if err := state.save(c.downloadStatePath()); err != nil {
+			globalCancel()
+			return err
+		}
+		saveWg.Add(1)
+		go func() {
+			defer saveWg.Done()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-saveDone:
+					return
+				case <-ticker.C:
+					_ = c.saveState(w, state)
+				}
+			}
+		}()
+	}
+
+	// 跟踪分段进度
+	c.tracker.reset(state.Size, state.Segments)
+
+	// 校验镜像，分段请求轮流发往健康的镜像
+	pool := c.newMirrorPool(globalCtx, state)
+
+	// 启动下载线程，从调度器领取分段直到全部下载完成
+	scheduler := newSegmentScheduler(state, c.tracker)
+	downloadThreadNum := c.downloadThreadNum(state.Size)
+	var i int64
+	for i = 0; i < downloadThreadNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for globalCtx.Err() == nil {
+				segment := scheduler.next()
+				if segment == nil {
+					return
+				}
+				err := c.downloadSegment(globalCtx, w, segment, state.validator(), pool, &responseOnce)
+				scheduler.done(segment)
+				if err != nil {
+					if globalCtx.Err() == nil {
+						setErr(err) // 记录错误并取消全部线程的下载
+					}
+					return
+				}
+			}
+		}()
+	}
+	// 等待全部线程下载完成
+	wg.Wait()
+	// 上下文结束
+	globalCancel()
+	// 停止定时保存
+	close(saveDone)
+	saveWg.Wait()
+	// 请求上下文被取消或超时
+	if err := c.context().Err(); err != nil {
+		return err
+	}
+	// 判断是否有错误信息
+	if downloadErr != nil {
+		// 下载失败，仅转换最终返回的错误响应（各线程的错误可能被重试或切换镜像）
+		c.decodeErrorBody(downloadErr)
+		return downloadErr
+	}
+	if globalCtx.Err() != nil && globalCtx.Err() != context.Canceled {
+		return globalCtx.Err()
+	}
+	// 下载成功
+	return nil
+}
+
+// downloadSegment 下载一个分段，中途失败时从已下载位置继续重试
+// @Desc 配置了镜像时，失败的分段改由其他健康的镜像下载；镜像切换与重试一样计入尝试次数并等待退避时间，
+// 最大尝试次数不少于镜像数量，保证每个镜像至少可以尝试一次
+// @params ctx          context.Context  下载上下文
+// @params w            io.WriterAt      写入目标
+// @params segment      *downloadSegment 下载分段
+// @params validator    string           If-Range校验值（为空时不校验）
+// @params pool         *mirrorPool      下载镜像池
+// @params responseOnce *sync.Once       仅赋值一次response
+// @return              error            错误信息
+func (c *BeClient) downloadSegment(ctx context.Context, w io.WriterAt, segment *downloadSegment, validator string, pool *mirrorPool, responseOnce *sync.Once) error {
+	maxAttempts := c.retryMaxAttempts()
+	if size := pool.size(); size > maxAttempts {
+		maxAttempts = size
+	}
+	for attempt := 1; ; attempt++ {
+		mirror := pool.pick()
+		c.tracker.setSegmentStatus(segment, segmentActive, nil)
+		retry, err := c.fetchSegment(ctx, w, segment, validator, mirror.url, responseOnce)
+		if err == nil {
+			pool.success(mirror)
+			c.tracker.setSegmentStatus(segment, segmentFinished, nil)
+			return nil
+		}
+		// 已取消的下载无需重试
+		if ctx.Err() != nil {
+			return err
+		}
+		// 是否可以改由其他镜像下载
+		switched := pool.failure(mirror, err)
+		// 不可重试或已达到最大尝试次数
+		if (!retry && !switched) || attempt >= maxAttempts {
+			return err
+		}
+		// 等待后重试（响应错误状态时遵循Retry-After）
+		c.tracker.setSegmentStatus(segment, segmentRetrying, err)
+		if err := c.retryWait(ctx, attempt, statusResponse(err)); err != nil {
+			return err
+		}
+	}
+}
+
+// fetchSegment 下载一个分段
+// @Desc 下载成功的字节会累加到分段的已下载量，重试时从已下载位置继续下载
+// @params ctx            context.Context  下载上下文
+// @params w              io.WriterAt      写入目标
+// @params segment        *downloadSegment 下载分段
+// @params validator      string           If-Range校验值（为空时不校验）
+// @params mirror         *url.URL         下载地址
+// @params responseOnce   *sync.Once       仅赋值一次response
+// @return retry          bool             错误是否可重试
+// @return err            error            错误信息
+func (c *BeClient) fetchSegment(ctx context.Context, w io.WriterAt, segment *downloadSegment, validator string, mirror *url.URL, responseOnce *sync.Once) (retry bool, err error) {
+	// 拷贝request
+	request, err := rewindRequest(c.request)
+	if err != nil {
+		return false, err
+	}
+	request = request.WithContext(ctx)
+	setRequestURL(request, mirror)
+	// 配置分段区域（下载过程中分段可能被拆分，结束偏移量只会变小）
+	start, end := segment.offset(), segment.end()
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	// 资源未变化时才返回分段内容
+	if len(validator) > 0 {
+		request.Header.Set("If-Range", validator)
+	}
+	// 发送请求（不经过c.do的重试，由downloadSegment统一重试，包括读取响应体时的错误）
+	res, err := c.roundTrip(request)
+	if err != nil {
+		return true, err
+	}
+	// 判断响应是否为空
+	if res == nil {
+		return true, errors.New("response is nil pointer address")
+	}
+	// 结束时释放
+	defer res.Body.Close()
+	// 赋值response(至于赋值第几个response并不需要关心)
+	responseOnce.Do(func() { c.response = res })
+	// 判断是否请求成功
+	switch res.StatusCode {
+	// 分段响应，需要校验返回的区域
+	case http.StatusPartialContent:
+		rangeStart, rangeEnd, _, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || rangeStart != start || rangeEnd != end {
+			return false, errRangeNotSupported
+		}
+		if res.ContentLength >= 0 && res.ContentLength != end-start+1 {
+			return false, errRangeNotSupported
+		}
+
+	// 完整响应，资源已变化或服务端忽略了Range
+	case http.StatusOK:
+		if len(validator) > 0 && !sameValidator(validator, res.Header) {
+			return false, ErrResourceChanged
+		}
+		return false, errRangeNotSupported
+
+	default:
+		// 将返回的错误信息读出
+		errBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return true, err
+		}
+		return c.retryStatus(res.StatusCode), newStatusError(res, errBody)
+	}
+
+	// 定义下载缓冲区
+	downBuffer := make([]byte, c.downloadBufferSize)
+	// 读取响应直到分段完成
+	for !segment.finished() {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+
+		default:
+			// 读取响应体
+			size, readErr := res.Body.Read(downBuffer)
+			// 判断是否发生错误
+			if readErr != nil && readErr != io.EOF {
+				return true, readErr
+			}
+			// 返回的内容超出了请求的区域
+			if int64(size) > end-start+1 {
+				return false, errRangeNotSupported
+			}
+			start += int64(size)
+			// 写入到文件（超出分段的部分已被拆分给其他线程）
+			n, err := segment.writeAt(w, downBuffer[:size])
+			// 赋值到全局总量
+			c.tracker.add(int64(n))
+			// 判断是否写入正确
+			if err != nil {
+				return false, err
+			}
+			// 响应提前结束
+			if readErr == io.EOF && !segment.finished() {
+				return true, io.ErrUnexpectedEOF
+			}
+		}
+	}
+	// 分段下载完成
+	return false, nil
+}
@@ -0,0 +1,133 @@
+package beclient
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultSyncInterval 默认定时同步间隔
+const defaultSyncInterval = time.Second
+
+// DownloadPreallocate 开启多线程下载的磁盘空间预分配
+// @Desc 下载前检查临时文件所在磁盘的可用空间（不足时返回ErrInsufficientSpace），并按资源大小预分配文件（Linux使用fallocate，其他系统使用truncate），减少文件碎片；
+// 可用空间检查支持Linux、macOS、FreeBSD及DragonFly BSD，其他系统（例如Windows）跳过检查仅预分配
+// @return *BeClient 客户端指针
+func (c *BeClient) DownloadPreallocate() *BeClient {
+	c.downloadPreallocate = true
+	return c
+}
+
+// DownloadSync 配置下载文件同步到磁盘的方式
+// @Desc 默认下载完成时同步一次，定时同步可以减少系统崩溃时断点续传状态与文件内容不一致的情况
+// @params mode     SyncModeType     同步方式
+// @params interval ...time.Duration 定时同步的间隔（默认1秒）
+// @return          *BeClient        客户端指针
+func (c *BeClient) DownloadSync(mode SyncModeType, interval ...time.Duration) *BeClient {
+	c.downloadSyncMode = mode
+	if len(interval) > 0 && interval[0] > 0 {
+		c.downloadSyncInterval = interval[0]
+	}
+	return c
+}
+
+// syncFile 下载完成时同步文件到磁盘
+// @params file *os.File 下载文件
+// @return      error    错误信息
+func (c *BeClient) syncFile(file *os.File) error {
+	if c.downloadSyncMode == SyncNone {
+		return nil
+	}
+	return file.Sync()
+}
+
+// periodicSync 定时同步文件到磁盘
+// @params file *os.File 下载文件
+// @return      func()   停止定时同步（会等待正在进行的同步结束）
+func (c *BeClient) periodicSync(file *os.File) func() {
+	if c.downloadSyncMode != SyncPeriodic {
+		return func() {}
+	}
+	interval := c.downloadSyncInterval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = file.Sync()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// saveState 保存断点续传状态
+// @Desc 定时同步时先记录进度再将文件同步到磁盘，最后写入状态，避免系统崩溃后状态记录的进度超过磁盘上的内容
+// @params w     io.WriterAt    写入目标
+// @params state *downloadState 下载状态
+// @return       error          错误信息
+func (c *BeClient) saveState(w io.WriterAt, state *downloadState) error {
+	content, err := state.encode()
+	if err != nil {
+		return err
+	}
+	if file, ok := w.(*os.File); ok && c.downloadSyncMode == SyncPeriodic {
+		if err = file.Sync(); err != nil {
+			return err
+		}
+	}
+	return writeDownloadState(c.downloadStatePath(), content)
+}
+
+// preallocateFile 检查磁盘空间并预分配下载文件
+// @params file      *os.File 下载文件
+// @params totalSize int64    资源总大小
+// @return           error    错误信息
+func (c *BeClient) preallocateFile(file *os.File, totalSize int64) error {
+	if !c.downloadPreallocate || totalSize <= 0 {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// 续传时已分配的部分无需额外空间
+	if need := totalSize - info.Size(); need > 0 {
+		if available, ok := freeSpace(filepath.Dir(file.Name())); ok && available < need {
+			return fmt.Errorf("%w: need %d bytes, available %d bytes", ErrInsufficientSpace, need, available)
+		}
+	}
+	return preallocate(file, totalSize)
+}
+
+// truncateTo 将文件扩展到指定大小
+// @Desc 文件已经不小于指定大小时不做处理，避免截断已下载的内容
+// @params file *os.File 文件
+// @params size int64    文件大小
+// @return      error    错误信息
+func truncateTo(file *os.File, size int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= size {
+		return nil
+	}
+	return file.Truncate(size)
+}
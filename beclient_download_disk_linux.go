@@ -0,0 +1,22 @@
+//go:build linux
+// +build linux
+
+package beclient
+
+import (
+	"os"
+	"syscall"
+)
+
+// preallocate 预分配文件空间
+// @Desc 使用fallocate分配磁盘块，文件系统不支持时退化为truncate
+// @params file *os.File 文件
+// @params size int64    文件大小
+// @return      error    错误信息
+func preallocate(file *os.File, size int64) error {
+	err := syscall.Fallocate(int(file.Fd()), 0, 0, size)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return truncateTo(file, size)
+	}
+	return err
+}
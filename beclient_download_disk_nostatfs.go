@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!dragonfly
+
+package beclient
+
+// freeSpace 获取路径所在磁盘的可用空间
+// @Desc 当前系统不支持，跳过可用空间检查
+// @params path string 路径
+// @return      int64  可用空间（byte）
+// @return      bool   是否获取成功
+func freeSpace(path string) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package beclient
+
+import "os"
+
+// preallocate 预分配文件空间
+// @Desc 非Linux系统通过truncate扩展文件大小
+// @params file *os.File 文件
+// @params size int64    文件大小
+// @return      error    错误信息
+func preallocate(file *os.File, size int64) error {
+	return truncateTo(file, size)
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || dragonfly
+// +build linux darwin freebsd dragonfly
+
+package beclient
+
+import "syscall"
+
+// freeSpace 获取路径所在磁盘的可用空间
+// @params path string 路径
+// @return      int64  可用空间（byte）
+// @return      bool   是否获取成功
+func freeSpace(path string) (int64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), true
+}
@@ -0,0 +1,102 @@
+package beclient
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// downloadTempSuffix 下载临时文件后缀
+const downloadTempSuffix = ".part"
+
+// DownloadTempDir 配置下载临时文件夹
+// @Desc 下载内容会先写入临时文件，成功（包括校验通过）后再重命名到保存路径，默认使用保存路径所在的文件夹；
+// 临时文件夹与保存路径不在同一文件系统时，会先拷贝到保存路径所在的文件夹再重命名
+// @params dir string    临时文件夹
+// @return     *BeClient 客户端指针
+func (c *BeClient) DownloadTempDir(dir string) *BeClient {
+	c.downloadTempDir = filepath.Clean(dir)
+	return c
+}
+
+// DownloadKeepPartial 配置下载失败时是否保留未完成的临时文件及断点续传状态
+// @Desc 默认开启断点续传时保留，否则删除
+// @params keep bool      是否保留
+// @return      *BeClient 客户端指针
+func (c *BeClient) DownloadKeepPartial(keep bool) *BeClient {
+	c.downloadKeepPartial = &keep
+	return c
+}
+
+// keepPartial 判断下载失败时是否保留未完成的文件
+// @return bool 是否保留
+func (c *BeClient) keepPartial() bool {
+	if c.downloadKeepPartial != nil {
+		return *c.downloadKeepPartial
+	}
+	return c.downloadResume
+}
+
+// downloadTempPath 获取下载临时文件路径
+// @Desc 使用临时文件夹时，文件名会附加保存路径的校验码以避免不同保存路径的同名文件冲突
+// @return string 临时文件路径
+func (c *BeClient) downloadTempPath() string {
+	if len(c.downloadTempDir) == 0 {
+		return c.downloadSavePath + downloadTempSuffix
+	}
+	absPath, err := filepath.Abs(c.downloadSavePath)
+	if err != nil {
+		absPath = c.downloadSavePath
+	}
+	name := fmt.Sprintf("%s.%08x%s", filepath.Base(c.downloadSavePath), crc32.ChecksumIEEE([]byte(absPath)), downloadTempSuffix)
+	return filepath.Join(c.downloadTempDir, name)
+}
+
+// moveFile 将临时文件原子替换到目标路径
+// @Desc 无法直接重命名时（例如跨文件系统），先拷贝到目标路径所在文件夹的临时文件，同步后再重命名
+// @params src string 临时文件路径
+// @params dst string 目标路径
+// @return     error  错误信息
+func moveFile(src, dst string) error {
+	// 直接重命名
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	// 拷贝到目标文件夹
+	tmpPath := dst + downloadTempSuffix
+	if err := copyFile(src, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile 拷贝文件并同步到磁盘
+// @params src string 源文件路径
+// @params dst string 目标文件路径
+// @return     error  错误信息
+func copyFile(src, dst string) error {
+	// 打开源文件
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	// 创建目标文件
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+	// 拷贝内容
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		return err
+	}
+	return dstFile.Sync()
+}
@@ -0,0 +1,168 @@
+package beclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// mirrorMaxFailures 镜像连续失败多少次后停止使用
+const mirrorMaxFailures = 3
+
+// downloadMirror 下载镜像
+type downloadMirror struct {
+	url      *url.URL // 镜像地址
+	failures int      // 连续失败次数
+	dropped  bool     // 是否已停止使用
+}
+
+// mirrorPool 下载镜像池
+// @Desc 多线程下载时分段请求轮流发往健康的镜像，镜像失败时分段改由其他镜像下载
+type mirrorPool struct {
+	mutex       sync.Mutex                // 镜像池锁
+	mirrors     []*downloadMirror         // 全部镜像（第一个为请求地址）
+	next        int                       // 下一次轮询的位置
+	retryStatus func(statusCode int) bool // 判断响应状态码是否可以重试
+}
+
+// DownloadMirrors 配置下载镜像
+// @Desc 多线程下载时分段会分散到请求地址及各个镜像下载，镜像需与请求地址的资源大小及ETag一致（与请求地址相同的方式探测，不一致的镜像不会被使用），
+// 连续失败的镜像会被停止使用，其未完成的分段由其他镜像继续下载（计入重试次数，最大尝试次数不少于镜像数量）；单线程下载及HLS下载仅使用请求地址
+// @params urls ...string 镜像地址（完整的URL）
+// @return      *BeClient 客户端指针
+func (c *BeClient) DownloadMirrors(urls ...string) *BeClient {
+	for _, val := range urls {
+		u, err := url.Parse(val)
+		if err != nil {
+			c.errMsg = err
+			return c
+		}
+		if !u.IsAbs() {
+			c.errMsg = errors.New("mirror url must be absolute: " + val)
+			return c
+		}
+		c.downloadMirrors = append(c.downloadMirrors, u)
+	}
+	return c
+}
+
+// newMirrorPool 创建下载镜像池
+// @Desc 请求地址总是可用，镜像需要探测与下载状态中的资源一致
+// @params ctx   context.Context 下载上下文
+// @params state *downloadState  下载状态
+// @return       *mirrorPool     下载镜像池
+func (c *BeClient) newMirrorPool(ctx context.Context, state *downloadState) *mirrorPool {
+	pool := &mirrorPool{mirrors: []*downloadMirror{{url: c.request.URL}}, retryStatus: c.retryStatus}
+	// 并发校验镜像
+	var wg sync.WaitGroup
+	mirrors := make([]*downloadMirror, len(c.downloadMirrors))
+	for i, u := range c.downloadMirrors {
+		wg.Add(1)
+		go func(i int, u *url.URL) {
+			defer wg.Done()
+			if c.probeMirror(ctx, u, state) {
+				mirrors[i] = &downloadMirror{url: u}
+			}
+		}(i, u)
+	}
+	wg.Wait()
+	for _, mirror := range mirrors {
+		if mirror != nil {
+			pool.mirrors = append(pool.mirrors, mirror)
+		}
+	}
+	return pool
+}
+
+// probeMirror 校验镜像资源是否与下载状态一致
+// @params ctx   context.Context 下载上下文
+// @params u     *url.URL        镜像地址
+// @params state *downloadState  下载状态
+// @return       bool            是否一致
+func (c *BeClient) probeMirror(ctx context.Context, u *url.URL, state *downloadState) bool {
+	stat, err := c.probeResource(ctx, u)
+	if err != nil || !stat.AcceptRanges || stat.Size != state.Size {
+		return false
+	}
+	// ETag需要一致，If-Range使用Last-Modified时Last-Modified也需要一致
+	if stat.ETag != state.ETag {
+		return false
+	}
+	if state.validator() == state.LastModified && stat.LastModified != state.LastModified {
+		return false
+	}
+	return true
+}
+
+// setRequestURL 修改请求地址
+// @params request *http.Request 请求体
+// @params u       *url.URL      请求地址
+func setRequestURL(request *http.Request, u *url.URL) {
+	copied := *u
+	request.URL = &copied
+	request.Host = u.Host
+}
+
+// pick 轮询选择一个健康的镜像
+// @return *downloadMirror 下载镜像
+func (p *mirrorPool) pick() *downloadMirror {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for range p.mirrors {
+		mirror := p.mirrors[p.next%len(p.mirrors)]
+		p.next++
+		if !mirror.dropped {
+			return mirror
+		}
+	}
+	// 不会发生，最后一个健康的镜像不会被停止使用
+	return p.mirrors[0]
+}
+
+// size 获取镜像数量（包括请求地址及已停止使用的镜像）
+// @return int 镜像数量
+func (p *mirrorPool) size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.mirrors)
+}
+
+// success 记录镜像下载成功
+// @params mirror *downloadMirror 下载镜像
+func (p *mirrorPool) success(mirror *downloadMirror) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	mirror.failures = 0
+}
+
+// failure 记录镜像下载失败
+// @Desc 资源不一致或响应不可重试的错误状态时立即停止使用该镜像，其他错误（包括可重试的状态码，例如503）连续达到mirrorMaxFailures次后停止使用，
+// 最后一个健康的镜像不会被停止使用
+// @params mirror *downloadMirror 下载镜像
+// @params err    error           错误信息
+// @return        bool            是否有其他健康的镜像可以重试
+func (p *mirrorPool) failure(mirror *downloadMirror, err error) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// 其他健康的镜像数量
+	healthy := 0
+	for _, item := range p.mirrors {
+		if item != mirror && !item.dropped {
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return false
+	}
+	mirror.failures++
+	var statusErr *StatusError
+	if mirror.failures >= mirrorMaxFailures ||
+		errors.Is(err, errRangeNotSupported) ||
+		errors.Is(err, ErrResourceChanged) ||
+		(errors.As(err, &statusErr) && !p.retryStatus(statusErr.StatusCode)) {
+		mirror.dropped = true
+	}
+	return true
+}
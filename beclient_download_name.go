@@ -0,0 +1,147 @@
+package beclient
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultDownloadFileName 无法推断文件名时使用的文件名
+const defaultDownloadFileName = "download"
+
+// DownloadDir 下载到文件夹
+// @Desc 文件名依次从Content-Disposition（支持RFC 5987的filename*）、重定向后最终地址的最后一段路径及Content-Type推断，
+// 推断出的文件名会去除路径及非法字符，下载完成后可通过GetSavePath获取保存路径
+// @params dir      string                   下载文件夹
+// @params callback DownloadCallbackFuncType 下载进度回调函数
+// @return          *BeClient                客户端指针
+func (c *BeClient) DownloadDir(dir string, callback DownloadCallbackFuncType) *BeClient {
+	c.Download("", callback)
+	c.downloadHLS = false
+	c.downloadDir = filepath.Clean(strings.TrimSpace(dir))
+	return c
+}
+
+// DownloadConflict 配置下载文件已存在时的处理方式
+// @Desc 默认覆盖已存在的文件，跳过下载时不返回错误
+// @params policy ConflictPolicyType 处理方式
+// @return        *BeClient          客户端指针
+func (c *BeClient) DownloadConflict(policy ConflictPolicyType) *BeClient {
+	c.downloadConflict = policy
+	return c
+}
+
+// GetSavePath 获取下载文件保存路径
+// @Desc 使用DownloadDir或自动重命名时，下载开始后才能确定保存路径
+// @return string 保存路径
+func (c *BeClient) GetSavePath() string {
+	return c.downloadSavePath
+}
+
+// resolveSavePath 确定下载文件保存路径
+// @Desc 使用DownloadDir时先探测资源以推断文件名（探测结果会复用于下载），然后按冲突处理方式处理已存在的文件
+// @return skip bool  是否跳过下载
+// @return err  error 错误信息
+func (c *BeClient) resolveSavePath() (skip bool, err error) {
+	// 根据响应推断文件名
+	if len(c.downloadDir) > 0 {
+		name := downloadFileName(nil, c.request.URL)
+		if stat, err := c.probeResource(c.context(), c.request.URL); err == nil {
+			c.downloadStat = stat
+			name = stat.FileName
+		}
+		c.downloadSavePath = filepath.Join(c.downloadDir, name)
+	}
+	// 文件不存在时无冲突
+	if _, err = os.Stat(c.downloadSavePath); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	switch c.downloadConflict {
+	case ConflictSkip:
+		return true, nil
+	case ConflictRename:
+		c.downloadSavePath, err = availablePath(c.downloadSavePath)
+		return false, err
+	}
+	return false, nil
+}
+
+// availablePath 获取不存在的文件路径
+// @Desc 依次尝试name (1).ext、name (2).ext……
+// @params savePath string 已存在的文件路径
+// @return          string 不存在的文件路径
+// @return          error  错误信息
+func availablePath(savePath string) (string, error) {
+	ext := filepath.Ext(savePath)
+	base := strings.TrimSuffix(savePath, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// downloadFileName 推断下载文件名
+// @Desc 依次使用Content-Disposition、地址的最后一段路径及Content-Type，均无法推断时为download
+// @params header http.Header 响应头（可为nil）
+// @params u      *url.URL    重定向后的最终地址
+// @return        string      文件名（已去除路径及非法字符）
+func downloadFileName(header http.Header, u *url.URL) string {
+	// Content-Disposition（mime会解码RFC 5987的filename*并优先于filename）
+	if val := header.Get("Content-Disposition"); len(val) > 0 {
+		if _, params, err := mime.ParseMediaType(val); err == nil {
+			if name := sanitizeFileName(params["filename"]); len(name) > 0 {
+				return name
+			}
+		}
+	}
+	// 地址的最后一段路径
+	if u != nil {
+		if name := sanitizeFileName(path.Base(u.Path)); len(name) > 0 {
+			return name
+		}
+	}
+	// 根据资源类型推断扩展名
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+			return defaultDownloadFileName + exts[0]
+		}
+	}
+	return defaultDownloadFileName
+}
+
+// sanitizeFileName 去除文件名中的路径及非法字符
+// @Desc 防止通过../等方式写入下载文件夹之外的路径
+// @params name string 文件名
+// @return      string 安全的文件名（无法使用时为空）
+func sanitizeFileName(name string) string {
+	// 只保留最后一段路径（同时处理Windows路径分隔符）
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+	if name == "/" {
+		return ""
+	}
+	// 替换控制字符及Windows保留字符
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	// Windows不允许以空格或.结尾
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	if len(name) == 0 || name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
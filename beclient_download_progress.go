@@ -0,0 +1,262 @@
+package beclient
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 分段状态
+const (
+	segmentPending  int32 = iota // 等待下载
+	segmentActive                // 下载中
+	segmentRetrying              // 等待重试
+	segmentFinished              // 已完成
+)
+
+// defaultProgressInterval 默认进度回调间隔
+const defaultProgressInterval = time.Millisecond * 200
+
+// downloadTracker 下载进度跟踪器
+// @Desc 配置了进度事件时，下载线程只累加计数，进度由单独的协程按间隔回调，避免在读取循环中执行回调；
+// 未配置时下载进度回调保持原有行为，在下载线程中每次读取后执行
+type downloadTracker struct {
+	doneSize  int64                    // 已下载量（原子操作）
+	totalSize int64                    // 资源总大小（原子操作，未知时为-1）
+	startSize int64                    // 本次下载开始时已下载的量（续传部分不计入速度）
+	startTime time.Time                // 开始时间
+	mutex     sync.Mutex               // 分段列表读写锁
+	segments  []*downloadSegment       // 下载分段
+	interval  time.Duration            // 回调间隔
+	callback  DownloadProgressFuncType // 进度事件回调
+	legacy    DownloadCallbackFuncType // 下载进度回调
+	events    chan *DownloadProgress   // 生命周期事件
+	stop      chan struct{}            // 停止信号
+	wg        sync.WaitGroup           // 等待回调协程结束
+	lastSize  int64                    // 上一次回调时的已下载量（仅回调协程访问）
+	lastTime  time.Time                // 上一次回调的时间（仅回调协程访问）
+}
+
+// DownloadEvents 配置下载进度事件回调
+// @Desc 回调在单独的协程中按间隔执行，除定时的进度事件外还包括开始、分段完成、重试、回退到单线程及完成事件；
+// 配置后Download注册的进度回调同样改为在该协程中按间隔执行（不再在每次读取后执行）
+// @params interval time.Duration            进度事件间隔（小于等于0时为200毫秒）
+// @params callback DownloadProgressFuncType 进度事件回调函数
+// @return          *BeClient                客户端指针
+func (c *BeClient) DownloadEvents(interval time.Duration, callback DownloadProgressFuncType) *BeClient {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	c.downloadProgressInterval = interval
+	c.downloadProgressFunc = callback
+	return c
+}
+
+// newDownloadTracker 创建下载进度跟踪器并启动回调协程
+// @return *downloadTracker 下载进度跟踪器
+func (c *BeClient) newDownloadTracker() *downloadTracker {
+	t := &downloadTracker{
+		totalSize: -1,
+		startTime: time.Now(),
+		interval:  c.downloadProgressInterval,
+		callback:  c.downloadProgressFunc,
+		legacy:    c.downloadCallFunc,
+		events:    make(chan *DownloadProgress, 64),
+		stop:      make(chan struct{}),
+	}
+	if t.interval <= 0 {
+		t.interval = defaultProgressInterval
+	}
+	t.lastTime = t.startTime
+	// 没有进度事件回调时无需启动协程
+	if t.callback == nil {
+		return t
+	}
+	t.wg.Add(1)
+	go t.run()
+	t.event(DownloadEventStart, nil, nil)
+	return t
+}
+
+// run 回调协程
+func (t *downloadTracker) run() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case progress := <-t.events:
+			t.deliver(progress)
+		case <-ticker.C:
+			t.deliver(t.snapshot(DownloadEventProgress, nil, nil))
+		case <-t.stop:
+			// 发送剩余的事件
+			for {
+				select {
+				case progress := <-t.events:
+					t.deliver(progress)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver 回调进度
+// @params progress *DownloadProgress 进度事件
+func (t *downloadTracker) deliver(progress *DownloadProgress) {
+	// 计算瞬时速度
+	now := time.Now()
+	if elapsed := now.Sub(t.lastTime).Seconds(); elapsed > 0 && progress.Event == DownloadEventProgress {
+		progress.Speed = float64(progress.DoneSize-t.lastSize) / elapsed
+		t.lastSize, t.lastTime = progress.DoneSize, now
+	}
+	if t.callback != nil {
+		t.callback(progress)
+	}
+	// 下载进度回调仅在进度变化时执行
+	if t.legacy != nil && (progress.Event == DownloadEventProgress || progress.Event == DownloadEventFinish) && progress.DoneSize > 0 {
+		t.legacy(float64(progress.DoneSize), float64(progress.TotalSize))
+	}
+}
+
+// finish 发送完成事件并停止回调协程
+// @params err error 下载结果
+func (t *downloadTracker) finish(err error) {
+	if t.callback == nil {
+		return
+	}
+	t.event(DownloadEventFinish, nil, err)
+	close(t.stop)
+	t.wg.Wait()
+}
+
+// event 发送生命周期事件
+// @params eventType DownloadEventType 事件类型
+// @params segment   *downloadSegment  相关分段（可为nil）
+// @params err       error             相关错误（可为nil）
+func (t *downloadTracker) event(eventType DownloadEventType, segment *downloadSegment, err error) {
+	if t.callback == nil {
+		return
+	}
+	t.events <- t.snapshot(eventType, segment, err)
+}
+
+// reset 重置下载进度
+// @Desc 开始新的下载阶段时调用（例如多线程下载回退到单线程下载）
+// @params totalSize int64              资源总大小（未知时为-1）
+// @params segments  []*downloadSegment 下载分段
+func (t *downloadTracker) reset(totalSize int64, segments []*downloadSegment) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.segments = append([]*downloadSegment(nil), segments...)
+	var doneSize int64
+	for _, segment := range segments {
+		doneSize += segment.done()
+	}
+	atomic.StoreInt64(&t.totalSize, totalSize)
+	atomic.StoreInt64(&t.doneSize, doneSize)
+	atomic.StoreInt64(&t.startSize, doneSize)
+}
+
+// addSegment 追加拆分出的分段
+// @params segment *downloadSegment 下载分段
+func (t *downloadTracker) addSegment(segment *downloadSegment) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.segments = append(t.segments, segment)
+}
+
+// add 累加已下载量
+// @Desc 未配置进度事件时直接执行下载进度回调
+// @params n int64 本次下载量
+func (t *downloadTracker) add(n int64) {
+	doneSize := atomic.AddInt64(&t.doneSize, n)
+	if n > 0 {
+		t.callLegacy(doneSize)
+	}
+}
+
+// complete 下载完成时将资源总大小确定为已下载量
+// @Desc 用于无法预先获取总大小的下载（例如HLS），未配置进度事件时会以确定的总大小再回调一次
+func (t *downloadTracker) complete() {
+	doneSize := atomic.LoadInt64(&t.doneSize)
+	atomic.StoreInt64(&t.totalSize, doneSize)
+	t.callLegacy(doneSize)
+}
+
+// callLegacy 未配置进度事件时执行下载进度回调
+// @params doneSize int64 已下载量
+func (t *downloadTracker) callLegacy(doneSize int64) {
+	if t.callback == nil && t.legacy != nil {
+		t.legacy(float64(doneSize), float64(atomic.LoadInt64(&t.totalSize)))
+	}
+}
+
+// setSegmentStatus 更新分段状态
+// @params segment *downloadSegment 下载分段
+// @params status  int32            分段状态
+// @params err     error            相关错误（可为nil）
+func (t *downloadTracker) setSegmentStatus(segment *downloadSegment, status int32, err error) {
+	atomic.StoreInt32(&segment.status, status)
+	switch status {
+	case segmentFinished:
+		t.event(DownloadEventSegmentComplete, segment, nil)
+	case segmentRetrying:
+		t.event(DownloadEventRetry, segment, err)
+	}
+}
+
+// snapshot 生成进度快照
+// @params eventType DownloadEventType 事件类型
+// @params segment   *downloadSegment  相关分段（可为nil）
+// @params err       error             相关错误（可为nil）
+// @return           *DownloadProgress 进度事件
+func (t *downloadTracker) snapshot(eventType DownloadEventType, segment *downloadSegment, err error) *DownloadProgress {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	progress := &DownloadProgress{
+		Event:     eventType,
+		DoneSize:  atomic.LoadInt64(&t.doneSize),
+		TotalSize: atomic.LoadInt64(&t.totalSize),
+		Elapsed:   time.Since(t.startTime),
+		ETA:       -1,
+		Err:       err,
+		Segments:  make([]DownloadSegmentProgress, 0, len(t.segments)),
+	}
+	// 平均速度及剩余时间
+	if seconds := progress.Elapsed.Seconds(); seconds > 0 {
+		progress.AverageSpeed = float64(progress.DoneSize-atomic.LoadInt64(&t.startSize)) / seconds
+	}
+	if progress.TotalSize >= 0 && progress.AverageSpeed > 0 {
+		progress.ETA = time.Duration(float64(progress.TotalSize-progress.DoneSize) / progress.AverageSpeed * float64(time.Second))
+	}
+	// 分段进度
+	for index, item := range t.segments {
+		segmentProgress := DownloadSegmentProgress{
+			Index: index,
+			Start: item.Start,
+			End:   item.end(),
+			Done:  item.done(),
+		}
+		switch atomic.LoadInt32(&item.status) {
+		case segmentActive:
+			progress.ActiveSegments++
+			segmentProgress.State = "active"
+		case segmentRetrying:
+			progress.RetryingSegments++
+			segmentProgress.State = "retrying"
+		case segmentFinished:
+			progress.FinishedSegments++
+			segmentProgress.State = "finished"
+		default:
+			segmentProgress.State = "pending"
+		}
+		progress.Segments = append(progress.Segments, segmentProgress)
+		if item == segment {
+			progress.Segment = &progress.Segments[len(progress.Segments)-1]
+		}
+	}
+	return progress
+}
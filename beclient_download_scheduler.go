@@ -0,0 +1,138 @@
+package beclient
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// minSegmentSize 分段的最小下载量（64KB）
+// @Desc 切分及拆分分段时每部分不小于该值，避免过多的小请求
+const minSegmentSize = 1024 * 64
+
+// segmentsPerThread 每个线程平均分配的分段数量
+const segmentsPerThread = 4
+
+// segmentScheduler 分段调度器
+// @Desc 下载线程从队列中领取分段，队列为空时拆分剩余量最大的下载中分段，使空闲线程分担慢速连接的下载量
+type segmentScheduler struct {
+	mutex   sync.Mutex         // 调度锁
+	state   *downloadState     // 下载状态（拆分出的分段会追加到状态中）
+	tracker *downloadTracker   // 下载进度跟踪器
+	pending []*downloadSegment // 等待下载的分段
+	active  []*downloadSegment // 下载中的分段
+}
+
+// newSegmentScheduler 创建分段调度器
+// @params state   *downloadState   下载状态
+// @params tracker *downloadTracker 下载进度跟踪器
+// @return         *segmentScheduler 分段调度器
+func newSegmentScheduler(state *downloadState, tracker *downloadTracker) *segmentScheduler {
+	s := &segmentScheduler{state: state, tracker: tracker}
+	for _, segment := range state.Segments {
+		if segment.finished() {
+			// 续传时已完成的分段
+			atomic.StoreInt32(&segment.status, segmentFinished)
+			continue
+		}
+		s.pending = append(s.pending, segment)
+	}
+	return s
+}
+
+// next 领取下一个需要下载的分段
+// @return *downloadSegment 下载分段（没有可下载的分段时为nil）
+func (s *segmentScheduler) next() *downloadSegment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// 优先领取队列中的分段
+	if len(s.pending) > 0 {
+		segment := s.pending[0]
+		s.pending = s.pending[1:]
+		s.active = append(s.active, segment)
+		return segment
+	}
+	// 拆分剩余量最大的下载中分段
+	var largest *downloadSegment
+	var largestRemaining int64
+	for _, segment := range s.active {
+		if remaining := segment.remaining(); remaining > largestRemaining {
+			largest, largestRemaining = segment, remaining
+		}
+	}
+	if largest == nil {
+		return nil
+	}
+	stolen := largest.split(minSegmentSize)
+	if stolen == nil {
+		return nil
+	}
+	s.state.addSegment(stolen)
+	s.tracker.addSegment(stolen)
+	s.active = append(s.active, stolen)
+	return stolen
+}
+
+// done 标记分段已结束下载
+// @params segment *downloadSegment 下载分段
+func (s *segmentScheduler) done(segment *downloadSegment) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i, item := range s.active {
+		if item == segment {
+			s.active = append(s.active[:i], s.active[i+1:]...)
+			return
+		}
+	}
+}
+
+// downloadThreadNum 根据资源大小及多线程参数计算下载线程数
+// @params totalSize int64 资源总大小
+// @return           int64 下载线程数
+func (c *BeClient) downloadThreadNum(totalSize int64) int64 {
+	// 根据大小计算需要的线程数
+	var downloadThreadNum = totalSize / c.downloadMaxSize
+	// 有余数时需要加一个线程
+	if totalSize%c.downloadMaxSize != 0 {
+		downloadThreadNum++
+	}
+	// 是否超过最大线程限制
+	if downloadThreadNum > c.downloadMaxThread {
+		// 重置为最大线程数
+		downloadThreadNum = c.downloadMaxThread
+	}
+	if downloadThreadNum < 1 {
+		downloadThreadNum = 1
+	}
+	return downloadThreadNum
+}
+
+// splitSegments 根据资源大小及多线程参数切分下载分段
+// @Desc 分段数量为线程数的数倍，由线程从队列中领取，每个分段不小于minSegmentSize（分段数量不少于线程数）
+// @params totalSize int64              资源总大小
+// @return           []*downloadSegment 下载分段
+func (c *BeClient) splitSegments(totalSize int64) []*downloadSegment {
+	downloadThreadNum := c.downloadThreadNum(totalSize)
+	// 计算分段数量
+	segmentNum := downloadThreadNum * segmentsPerThread
+	if totalSize/segmentNum < minSegmentSize {
+		segmentNum = totalSize / minSegmentSize
+	}
+	if segmentNum < downloadThreadNum {
+		segmentNum = downloadThreadNum
+	}
+	// 根据分段数量计算每个分段的下载容量，余数由最后一个分段下载
+	segmentSize := totalSize / segmentNum
+	segments := make([]*downloadSegment, 0, segmentNum)
+	var i int64
+	for i = 0; i < segmentNum; i++ {
+		// 计算下载偏移量Range为闭区间
+		offsetStart := i * segmentSize
+		offsetEnd := (i+1)*segmentSize - 1
+		// 是否为最后一个分段
+		if i+1 == segmentNum {
+			offsetEnd = totalSize - 1
+		}
+		segments = append(segments, &downloadSegment{Start: offsetStart, End: offsetEnd})
+	}
+	return segments
+}
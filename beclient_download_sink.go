@@ -0,0 +1,156 @@
+package beclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// offsetWriter 将顺序写入转换为随机写入
+type offsetWriter struct {
+	w      io.WriterAt // 随机写入目标
+	offset int64       // 当前写入偏移量
+}
+
+// Write 实现io.Writer接口
+// @params p []byte 写入内容
+// @return n int    写入长度
+// @return   error  错误信息
+func (o *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// DownloadTo 标记当前请求为下载类型，并将内容顺序写入到w
+// @Desc 使用单个连接下载，不会写入文件（不支持断点续传及临时文件），配置的校验值及响应头中的校验值会在下载过程中校验
+// @params w io.Writer 写入目标（例如内存缓冲区、上传器、解压器）
+// @return   *BeClient 客户端指针
+func (c *BeClient) DownloadTo(w io.Writer) *BeClient {
+	if w == nil {
+		c.errMsg = errors.New("download writer is nil")
+		return c
+	}
+	c.isDownloadRequest = true
+	c.downloadWriter = w
+	return c
+}
+
+// DownloadToWriterAt 标记当前请求为下载类型，并将内容按偏移量写入到w
+// @Desc 服务端支持Range时使用多线程分段下载，否则退化为单个连接顺序写入；
+// 目标同时实现io.ReaderAt时，下载完成后会读取内容进行校验
+// @params w    io.WriterAt 写入目标
+// @params size int64       期望的资源大小（大于0时与服务端返回的大小不一致将返回错误）
+// @return      *BeClient   客户端指针
+func (c *BeClient) DownloadToWriterAt(w io.WriterAt, size int64) *BeClient {
+	if w == nil {
+		c.errMsg = errors.New("download writer is nil")
+		return c
+	}
+	c.isDownloadRequest = true
+	c.downloadWriterAt = w
+	c.downloadWriterAtSize = size
+	return c
+}
+
+// streamDownload 使用单个连接下载并顺序写入
+// @params w io.Writer 写入目标
+// @return   error     错误信息
+func (c *BeClient) streamDownload(w io.Writer) error {
+	// 重建请求（HEAD探测可能已读取请求Body）
+	request, err := rewindRequest(c.request)
+	if err != nil {
+		return err
+	}
+	// 发送请求
+	res, err := c.do(request)
+	if err != nil {
+		return err
+	}
+	// 判断响应是否为空
+	if res == nil {
+		return errors.New("response is nil pointer address")
+	}
+	// 结束时释放
+	defer res.Body.Close()
+	// 赋值response
+	c.response = res
+	// 判断是否请求成功
+	if res.StatusCode != http.StatusOK {
+		// 将返回的错误信息读出
+		errBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		return c.statusError(res, errBody)
+	}
+	// 校验资源大小
+	if err = c.checkWriterAtSize(res.ContentLength); err != nil {
+		return err
+	}
+	// 边下载边计算哈希
+	hasher := c.newDownloadHasher(checksumHeader(res), false)
+	// 读取响应直到结束
+	if err = c.copyBody(w, res.Body, hasher, &downloadSegment{Start: 0, End: res.ContentLength - 1}); err != nil {
+		return err
+	}
+	return c.verifyDownload(hasher)
+}
+
+// writerAtDownload 下载并按偏移量写入
+// @return error 错误信息
+func (c *BeClient) writerAtDownload() error {
+	// 探测是否支持多线程下载
+	stat, ok := c.probeDownload()
+	if !ok {
+		// 退化为顺序写入
+		return c.streamDownload(&offsetWriter{w: c.downloadWriterAt})
+	}
+	// 校验资源大小
+	totalSize := stat.Size
+	if err := c.checkWriterAtSize(totalSize); err != nil {
+		return err
+	}
+	// 多线程下载（不保存断点续传状态）
+	state := newDownloadState(downloadModeMulti, c.request.URL.String(), totalSize, stat.Header, c.splitSegments(totalSize))
+	err := c.rangeDownload(c.downloadWriterAt, state, false)
+	// 服务端未正确响应Range时退化为顺序写入
+	if errors.Is(err, errRangeNotSupported) {
+		c.tracker.event(DownloadEventFallback, nil, err)
+		return c.streamDownload(&offsetWriter{w: c.downloadWriterAt})
+	}
+	if err != nil {
+		return err
+	}
+	// 可读取时校验内容
+	hasher := c.newDownloadHasher(stat.Header, false)
+	if hasher == nil {
+		return nil
+	}
+	readerAt, ok := c.downloadWriterAt.(io.ReaderAt)
+	if !ok {
+		if len(c.downloadChecksums) > 0 {
+			return errors.New("download writer does not implement io.ReaderAt, cannot verify checksum")
+		}
+		return nil
+	}
+	if _, err = io.Copy(hasher, io.NewSectionReader(readerAt, 0, totalSize)); err != nil {
+		return err
+	}
+	return c.verifyDownload(hasher)
+}
+
+// checkWriterAtSize 校验资源大小是否与随机写入目标的期望大小一致
+// @params size int64 服务端返回的资源大小
+// @return      error 错误信息
+func (c *BeClient) checkWriterAtSize(size int64) error {
+	if c.downloadWriterAt == nil || c.downloadWriterAtSize <= 0 || size < 0 {
+		return nil
+	}
+	if size != c.downloadWriterAtSize {
+		return fmt.Errorf("download size mismatch: expected %d, server reported %d", c.downloadWriterAtSize, size)
+	}
+	return nil
+}
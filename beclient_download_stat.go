@@ -0,0 +1,173 @@
+package beclient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Stat 获取远程资源信息
+// @Desc 不下载资源内容，优先发送HEAD请求，HEAD失败或未声明支持Range时发送Range为bytes=0-0的GET请求探测
+// @return *DownloadStat 资源信息
+// @return error         错误信息
+func (c *BeClient) Stat() (*DownloadStat, error) {
+	// 是否有全局异常
+	if c.errMsg != nil {
+		return nil, c.errMsg
+	}
+	// 探测使用GET请求
+	c.method = MethodGet
+	// 判断是否已经构建
+	if c.client == nil || c.request == nil {
+		// 执行构建
+		if err := c.build(); err != nil {
+			return nil, err
+		}
+	}
+	// 结束时释放请求体
+	defer c.request.Body.Close()
+	stat, err := c.probeResource(c.context(), c.request.URL)
+	// 探测错误返回给调用方时才转换错误响应内容
+	c.decodeErrorBody(err)
+	return stat, err
+}
+
+// probeResource 探测远程资源信息
+// @Desc 先发送HEAD请求，HEAD失败或未声明支持Range时发送Range GET请求，不修改c.request
+// @params ctx context.Context 请求上下文
+// @params u   *url.URL        资源地址
+// @return     *DownloadStat   资源信息
+// @return     error           错误信息
+func (c *BeClient) probeResource(ctx context.Context, u *url.URL) (*DownloadStat, error) {
+	// 发送HEAD请求
+	stat, headErr := c.probeHead(ctx, u)
+	if headErr == nil && stat.AcceptRanges {
+		return stat, nil
+	}
+	// HEAD失败或未声明支持Range时发送Range GET请求
+	rangeStat, err := c.probeRange(ctx, u)
+	if err == nil {
+		return rangeStat, nil
+	}
+	// Range GET失败时使用HEAD的结果
+	if headErr == nil {
+		return stat, nil
+	}
+	return nil, err
+}
+
+// probeRequest 基于c.request创建探测请求
+// @params ctx    context.Context 请求上下文
+// @params u      *url.URL        资源地址
+// @params method MethodType      请求类型
+// @return        *http.Request   请求体
+// @return        error           错误信息
+func (c *BeClient) probeRequest(ctx context.Context, u *url.URL, method MethodType) (*http.Request, error) {
+	request, err := rewindRequest(c.request)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Method = string(method)
+	setRequestURL(request, u)
+	return request, nil
+}
+
+// probeHead 发送HEAD请求探测资源
+// @params ctx context.Context 请求上下文
+// @params u   *url.URL        资源地址
+// @return     *DownloadStat   资源信息
+// @return     error           错误信息
+func (c *BeClient) probeHead(ctx context.Context, u *url.URL) (*DownloadStat, error) {
+	request, err := c.probeRequest(ctx, u, MethodHead)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(request)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("http.Response is nil pointer")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, newStatusError(res, nil)
+	}
+	stat := newDownloadStat(res, res.ContentLength, res.Header)
+	stat.AcceptRanges = res.ContentLength >= 0 && strings.Contains(res.Header.Get("Accept-Ranges"), "bytes")
+	return stat, nil
+}
+
+// probeRange 发送Range为bytes=0-0的GET请求探测资源
+// @Desc 响应206时从Content-Range读取资源总大小
+// @params ctx context.Context 请求上下文
+// @params u   *url.URL        资源地址
+// @return     *DownloadStat   资源信息
+// @return     error           错误信息
+func (c *BeClient) probeRange(ctx context.Context, u *url.URL) (*DownloadStat, error) {
+	request, err := c.probeRequest(ctx, u, MethodGet)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Range", "bytes=0-0")
+	res, err := c.do(request)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("http.Response is nil pointer")
+	}
+	// 完整响应无需读取，直接关闭
+	defer res.Body.Close()
+	switch res.StatusCode {
+	// 支持Range
+	case http.StatusPartialContent:
+		start, _, size, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || start != 0 {
+			size = -1
+		}
+		header := res.Header.Clone()
+		// Content-MD5为分段内容的校验值，不能用于校验完整资源
+		header.Del("Content-MD5")
+		stat := newDownloadStat(res, size, header)
+		stat.AcceptRanges = size >= 0
+		return stat, nil
+
+	// 忽略了Range
+	case http.StatusOK:
+		return newDownloadStat(res, res.ContentLength, res.Header), nil
+
+	// 资源为空
+	case http.StatusRequestedRangeNotSatisfiable:
+		if val := res.Header.Get("Content-Range"); val == "bytes */0" {
+			return newDownloadStat(res, 0, res.Header), nil
+		}
+	}
+	// 将返回的错误信息读出
+	errBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return nil, newStatusError(res, errBody)
+}
+
+// newDownloadStat 根据探测响应创建资源信息
+// @params res    *http.Response 探测响应
+// @params size   int64          资源总大小
+// @params header http.Header    响应头
+// @return        *DownloadStat  资源信息
+func newDownloadStat(res *http.Response, size int64, header http.Header) *DownloadStat {
+	return &DownloadStat{
+		Size:         size,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		ContentType:  header.Get("Content-Type"),
+		URL:          res.Request.URL.String(),
+		FileName:     downloadFileName(header, res.Request.URL),
+		Header:       header,
+	}
+}
@@ -0,0 +1,324 @@
+package beclient
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// downloadStateSuffix 断点续传状态文件后缀
+const downloadStateSuffix = ".beclient"
+
+// 断点续传状态的下载模式
+const (
+	downloadModeSingle = "single" // 单线程下载（以文件大小作为已下载位置）
+	downloadModeMulti  = "multi"  // 多线程下载（以分段记录已下载位置）
+)
+
+// downloadSegment 下载分段
+type downloadSegment struct {
+	mutex  sync.Mutex // 分段读写锁（分段可能在下载过程中被拆分）
+	Start  int64      `json:"start"` // 分段起始偏移量（闭区间）
+	End    int64      `json:"end"`   // 分段结束偏移量（闭区间，资源大小未知时为-2）
+	Done   int64      `json:"done"`  // 分段已下载量
+	status int32      // 分段状态（原子操作）
+}
+
+// downloadState 断点续传状态
+type downloadState struct {
+	mutex        sync.Mutex         // 保存文件的互斥锁
+	Mode         string             `json:"mode"`         // 下载模式
+	URL          string             `json:"url"`          // 下载地址
+	Size         int64              `json:"size"`         // 资源总大小（未知时为-1）
+	ETag         string             `json:"etag"`         // 资源ETag
+	LastModified string             `json:"lastModified"` // 资源最后修改时间
+	Segments     []*downloadSegment `json:"segments"`     // 下载分段
+}
+
+// DownloadResume 开启断点续传
+// @Desc 未完成的内容保存在临时文件中，下载进度会保存在<savePath>.beclient状态文件中，下次下载同一路径时会校验远程资源的ETag、Last-Modified及大小，
+// 未发生变化时仅下载缺失的区域（通过If-Range保证一致），下载成功后状态文件会被删除
+// @return *BeClient 客户端指针
+func (c *BeClient) DownloadResume() *BeClient {
+	c.downloadResume = true
+	return c
+}
+
+// downloadStatePath 获取断点续传状态文件路径
+// @return string 状态文件路径
+func (c *BeClient) downloadStatePath() string {
+	return c.downloadSavePath + downloadStateSuffix
+}
+
+// newDownloadState 创建断点续传状态
+// @params mode     string             下载模式
+// @params url      string             下载地址
+// @params size     int64              资源总大小
+// @params header   http.Header        响应头
+// @params segments []*downloadSegment 下载分段
+// @return          *downloadState     断点续传状态
+func newDownloadState(mode, url string, size int64, header http.Header, segments []*downloadSegment) *downloadState {
+	return &downloadState{
+		Mode:         mode,
+		URL:          url,
+		Size:         size,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Segments:     segments,
+	}
+}
+
+// loadDownloadState 读取断点续传状态
+// @params path string         状态文件路径
+// @return      *downloadState 断点续传状态（不存在或无法解析时为nil）
+func loadDownloadState(path string) *downloadState {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	state := new(downloadState)
+	if err = json.Unmarshal(content, state); err != nil {
+		return nil
+	}
+	// 校验分段是否有效
+	for _, segment := range state.Segments {
+		if segment == nil || segment.Done < 0 || segment.Start+segment.Done > segment.End+1 {
+			return nil
+		}
+	}
+	return state
+}
+
+// removeDownloadState 删除断点续传状态文件
+// @params path string 状态文件路径
+// @return      error  错误信息
+func removeDownloadState(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// save 保存断点续传状态
+// @params path string 状态文件路径
+// @return      error  错误信息
+func (s *downloadState) save(path string) error {
+	content, err := s.encode()
+	if err != nil {
+		return err
+	}
+	return writeDownloadState(path, content)
+}
+
+// encode 编码当前的断点续传状态
+// @return []byte 状态内容
+// @return error  错误信息
+func (s *downloadState) encode() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// 拷贝分段进度
+	snapshot := struct {
+		Mode         string            `json:"mode"`
+		URL          string            `json:"url"`
+		Size         int64             `json:"size"`
+		ETag         string            `json:"etag"`
+		LastModified string            `json:"lastModified"`
+		Segments     []downloadSegment `json:"segments"`
+	}{
+		Mode:         s.Mode,
+		URL:          s.URL,
+		Size:         s.Size,
+		ETag:         s.ETag,
+		LastModified: s.LastModified,
+		Segments:     make([]downloadSegment, 0, len(s.Segments)),
+	}
+	for _, segment := range s.Segments {
+		snapshot.Segments = append(snapshot.Segments, downloadSegment{
+			Start: segment.Start,
+			End:   segment.end(),
+			Done:  segment.done(),
+		})
+	}
+	return json.Marshal(snapshot)
+}
+
+// writeDownloadState 写入断点续传状态文件
+// @Desc 先写入临时文件再重命名，避免写入中断导致状态文件损坏
+// @params path    string 状态文件路径
+// @params content []byte 状态内容
+// @return         error  错误信息
+func writeDownloadState(path string, content []byte) error {
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// validator 获取If-Range校验值
+// @Desc 优先使用强ETag，弱ETag不能用于If-Range，此时使用Last-Modified
+// @return string 校验值（为空时无法校验）
+func (s *downloadState) validator() string {
+	if len(s.ETag) > 0 && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// matches 判断远程资源是否与状态一致
+// @params size   int64       资源总大小
+// @params header http.Header 响应头
+// @return        bool        是否一致
+func (s *downloadState) matches(size int64, header http.Header) bool {
+	return len(s.validator()) > 0 &&
+		s.Size == size &&
+		s.ETag == header.Get("ETag") &&
+		s.LastModified == header.Get("Last-Modified")
+}
+
+// sameValidator 判断响应头中的校验值是否与If-Range校验值一致
+// @params validator string      If-Range校验值
+// @params header    http.Header 响应头
+// @return           bool        是否一致
+func sameValidator(validator string, header http.Header) bool {
+	return validator == header.Get("ETag") || validator == header.Get("Last-Modified")
+}
+
+// addSegment 追加下载分段
+// @params segment *downloadSegment 下载分段
+func (s *downloadState) addSegment(segment *downloadSegment) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Segments = append(s.Segments, segment)
+}
+
+// done 获取分段已下载量
+// @return int64 已下载量
+func (s *downloadSegment) done() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.Done
+}
+
+// end 获取分段结束偏移量
+// @return int64 结束偏移量
+func (s *downloadSegment) end() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.End
+}
+
+// add 累加分段已下载量
+// @params n int64 本次下载量
+func (s *downloadSegment) add(n int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Done += n
+}
+
+// offset 获取分段下一次下载的偏移量
+// @return int64 偏移量
+func (s *downloadSegment) offset() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.Start + s.Done
+}
+
+// remaining 获取分段剩余的下载量
+// @return int64 剩余下载量
+func (s *downloadSegment) remaining() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.End - (s.Start + s.Done) + 1
+}
+
+// finished 判断分段是否下载完成
+// @return bool 是否完成
+func (s *downloadSegment) finished() bool {
+	return s.remaining() <= 0
+}
+
+// writeAt 将内容写入分段的下一个偏移量
+// @Desc 超出分段结束偏移量的内容（已被拆分给其他线程）会被丢弃，写入与拆分互斥，保证同一区域只由一个线程写入
+// @params w io.WriterAt 写入目标
+// @params p []byte      写入内容
+// @return   int         实际写入的字节数
+// @return   error       错误信息
+func (s *downloadSegment) writeAt(w io.WriterAt, p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	offset := s.Start + s.Done
+	if remaining := s.End - offset + 1; int64(len(p)) > remaining {
+		if remaining < 0 {
+			remaining = 0
+		}
+		p = p[:remaining]
+	}
+	n, err := w.WriteAt(p, offset)
+	s.Done += int64(n)
+	return n, err
+}
+
+// split 拆分分段剩余区域的后半部分
+// @params minSize int64            拆分后每部分的最小下载量
+// @return         *downloadSegment 拆分出的新分段（剩余区域不足时为nil）
+func (s *downloadSegment) split(minSize int64) *downloadSegment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	offset := s.Start + s.Done
+	remaining := s.End - offset + 1
+	if remaining < minSize*2 {
+		return nil
+	}
+	middle := offset + remaining/2
+	stolen := &downloadSegment{Start: middle, End: s.End}
+	s.End = middle - 1
+	return stolen
+}
+
+// parseContentRange 解析Content-Range响应头
+// @params val   string 响应头内容（例如：bytes 0-99/1000）
+// @return start int64  起始偏移量
+// @return end   int64  结束偏移量
+// @return size  int64  资源总大小（未知时为-1）
+// @return ok    bool   是否解析成功
+func parseContentRange(val string) (start, end, size int64, ok bool) {
+	// 截取单位
+	if !strings.HasPrefix(val, "bytes ") {
+		return 0, 0, 0, false
+	}
+	val = strings.TrimSpace(strings.TrimPrefix(val, "bytes "))
+	// 截取范围及总大小
+	index := strings.Index(val, "/")
+	if index < 0 {
+		return 0, 0, 0, false
+	}
+	rangePart, sizePart := val[:index], val[index+1:]
+	// 解析总大小
+	size = -1
+	if sizePart != "*" {
+		var err error
+		if size, err = strconv.ParseInt(sizePart, 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	// 解析范围
+	index = strings.Index(rangePart, "-")
+	if index < 0 {
+		return 0, 0, 0, false
+	}
+	start, err := strconv.ParseInt(rangePart[:index], 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	end, err = strconv.ParseInt(rangePart[index+1:], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, false
+	}
+	return start, end, size, true
+}
@@ -0,0 +1,96 @@
+package beclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error 实现error接口
+// @return string 错误信息
+func (e *StatusError) Error() string {
+	// 错误信息中的响应内容最多保留256字节
+	body := e.Body
+	if len(body) > 256 {
+		body = body[:256]
+	}
+	if len(body) == 0 {
+		return fmt.Sprintf("unexpected response status: %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected response status: %s: %s", e.Status, body)
+}
+
+// StatusCheck 将非2xx响应视为错误
+// @Desc 开启后非2xx响应将返回*StatusError，不再转换到响应变量中
+// @return *BeClient 客户端指针
+func (c *BeClient) StatusCheck() *BeClient {
+	c.statusCheck = true
+	return c
+}
+
+// ErrorBody 配置非2xx响应内容的接收变量
+// @Desc 调用后自动开启StatusCheck，非2xx响应内容将按资源类型转换到该变量中，同时返回*StatusError
+// @params dst            interface{}        指定类型的变量指针
+// @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
+// @return                *BeClient          客户端指针
+func (c *BeClient) ErrorBody(dst interface{}, resContentType ...ContentTypeType) *BeClient {
+	c.statusCheck = true
+	c.errorBody = dst
+	c.errorBodyContentType = resContentType
+	return c
+}
+
+// statusError 根据响应创建状态错误
+// @Desc 配置了ErrorBody时会将响应内容转换到接收变量中，仅用于会返回给调用方的错误
+// @params res  *http.Response 响应体
+// @params body []byte         已读取的响应内容
+// @return      error          *StatusError
+func (c *BeClient) statusError(res *http.Response, body []byte) error {
+	err := newStatusError(res, body)
+	c.decodeErrorBody(err)
+	return err
+}
+
+// newStatusError 根据响应创建状态错误
+// @Desc 不转换响应内容，用于可能被回退处理而不返回给调用方的错误（例如下载探测）
+// @params res  *http.Response 响应体
+// @params body []byte         已读取的响应内容
+// @return      *StatusError   状态错误
+func newStatusError(res *http.Response, body []byte) *StatusError {
+	return &StatusError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+		Body:       body,
+	}
+}
+
+// decodeErrorBody 将状态错误的响应内容转换到ErrorBody配置的接收变量中
+// @Desc 转换失败不影响状态错误的返回
+// @params err error 错误信息（不是状态错误时忽略）
+func (c *BeClient) decodeErrorBody(err error) {
+	var statusErr *StatusError
+	if c.errorBody == nil || !errors.As(err, &statusErr) {
+		return
+	}
+	_ = c.responseConvertData(statusErr.Header, statusErr.Body, c.errorBody, c.errorBodyContentType...)
+}
+
+// statusResponse 根据状态错误还原响应（仅包含状态码及响应头）
+// @Desc 用于在响应已关闭后读取Retry-After
+// @params err error          错误信息
+// @return     *http.Response 响应（不是状态错误时为nil）
+func statusResponse(err error) *http.Response {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return nil
+	}
+	return &http.Response{StatusCode: statusErr.StatusCode, Status: statusErr.Status, Header: statusErr.Header}
+}
+
+// isSuccessStatus 判断是否为2xx状态码
+// @params statusCode int  状态码
+// @return            bool 是否成功
+func isSuccessStatus(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
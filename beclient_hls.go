@@ -0,0 +1,657 @@
+package beclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// hlsMaxPlaylistSize 播放列表最大读取长度
+const hlsMaxPlaylistSize = 1024 * 1024 * 10
+
+// hlsKey HLS分段加密信息
+type hlsKey struct {
+	uri string // 密钥地址
+	iv  []byte // 初始向量（为空时使用分段序号）
+}
+
+// hlsByteRange HLS分段字节范围（#EXT-X-BYTERANGE）
+type hlsByteRange struct {
+	offset int64 // 起始偏移量
+	length int64 // 长度
+}
+
+// hlsSegment HLS分段
+type hlsSegment struct {
+	uri       string        // 分段地址
+	byteRange *hlsByteRange // 字节范围（为nil时为整个资源）
+	sequence  int64         // 分段序号
+	key       *hlsKey       // 加密信息（为nil时未加密）
+}
+
+// hlsPlaylist HLS播放列表
+type hlsPlaylist struct {
+	variants  []*HLSVariant // 清晰度列表（仅主播放列表）
+	initURI   string        // 初始化分段地址（#EXT-X-MAP）
+	initRange *hlsByteRange // 初始化分段字节范围（为nil时为整个资源）
+	segments  []*hlsSegment // 媒体分段（仅媒体播放列表）
+}
+
+// DownloadHLS 标记当前请求为HLS（m3u8）下载类型
+// @Desc 请求地址为主播放列表时会按清晰度选择函数选择媒体播放列表（默认选择带宽最高的），
+// 分段按多线程参数并发下载（支持#EXT-X-BYTERANGE），AES-128加密的分段会被解密，最终按顺序合并为一个.ts文件；
+// 进度回调的参数与其他下载方式一致为已下载的字节数，资源总大小在全部分段下载完成前未知（为-1）；
+// 不支持与DownloadTo、DownloadToWriterAt同时使用（返回错误）
+// @params savePath string                   下载资源文件保存路径
+// @params callback DownloadCallbackFuncType 下载进度回调函数(请勿在回调函数内处理过多业务，否则可能会造成响应超时)
+// @return          *BeClient                客户端指针
+func (c *BeClient) DownloadHLS(savePath string, callback DownloadCallbackFuncType) *BeClient {
+	c.Download(savePath, callback)
+	c.downloadHLS = true
+	return c
+}
+
+// HLSVariantSelector 配置HLS清晰度选择函数
+// @params selector HLSVariantSelectFuncType 清晰度选择函数
+// @return          *BeClient                客户端指针
+func (c *BeClient) HLSVariantSelector(selector HLSVariantSelectFuncType) *BeClient {
+	c.hlsVariantSelector = selector
+	return c
+}
+
+// HLSBandwidthHighest 选择带宽最高的清晰度
+// @params variants []*HLSVariant 清晰度列表
+// @return          *HLSVariant   选中的清晰度
+func HLSBandwidthHighest(variants []*HLSVariant) *HLSVariant {
+	var selected *HLSVariant
+	for _, variant := range variants {
+		if selected == nil || variant.Bandwidth > selected.Bandwidth {
+			selected = variant
+		}
+	}
+	return selected
+}
+
+// HLSBandwidthLowest 选择带宽最低的清晰度
+// @params variants []*HLSVariant 清晰度列表
+// @return          *HLSVariant   选中的清晰度
+func HLSBandwidthLowest(variants []*HLSVariant) *HLSVariant {
+	var selected *HLSVariant
+	for _, variant := range variants {
+		if selected == nil || variant.Bandwidth < selected.Bandwidth {
+			selected = variant
+		}
+	}
+	return selected
+}
+
+// HLSResolutionAtMost 创建按分辨率选择的函数
+// @Desc 选择不超过指定分辨率的最高清晰度，均超过时选择分辨率最低的
+// @params width  int64                    最大宽度
+// @params height int64                    最大高度
+// @return        HLSVariantSelectFuncType 清晰度选择函数
+func HLSResolutionAtMost(width, height int64) HLSVariantSelectFuncType {
+	return func(variants []*HLSVariant) *HLSVariant {
+		var selected, lowest *HLSVariant
+		for _, variant := range variants {
+			pixels := variant.Width * variant.Height
+			if lowest == nil || pixels < lowest.Width*lowest.Height {
+				lowest = variant
+			}
+			if variant.Width > width || variant.Height > height {
+				continue
+			}
+			if selected == nil || pixels > selected.Width*selected.Height ||
+				(pixels == selected.Width*selected.Height && variant.Bandwidth > selected.Bandwidth) {
+				selected = variant
+			}
+		}
+		if selected == nil {
+			return lowest
+		}
+		return selected
+	}
+}
+
+// hlsDownload HLS下载
+// @Desc 内容会被写入临时文件
+func (c *BeClient) hlsDownload() (err error) {
+	// 分段由多个协程下载，仅转换最终返回的错误响应
+	defer func() { c.decodeErrorBody(err) }()
+	// 初始化可取消上下文（继承请求上下文，超时时间作为额外的截止时间）
+	ctx, cancel := c.withTimeout(c.context())
+	defer cancel()
+	// 获取播放列表
+	playlist, err := c.fetchHLSPlaylist(ctx, c.request)
+	if err != nil {
+		return err
+	}
+	// 主播放列表需要选择清晰度
+	if len(playlist.variants) > 0 {
+		selector := c.hlsVariantSelector
+		if selector == nil {
+			selector = HLSBandwidthHighest
+		}
+		variant := selector(playlist.variants)
+		if variant == nil {
+			return errors.New("no hls variant selected")
+		}
+		// 获取媒体播放列表
+		request, err := c.hlsRequest(ctx, variant.URI)
+		if err != nil {
+			return err
+		}
+		if playlist, err = c.fetchHLSPlaylist(ctx, request); err != nil {
+			return err
+		}
+		if len(playlist.variants) > 0 {
+			return errors.New("hls variant playlist is a master playlist")
+		}
+	}
+	// 没有分段
+	if len(playlist.segments) == 0 {
+		return errors.New("hls playlist has no segments")
+	}
+
+	// 打开文件，边下载边写入(os.O_TRUNC覆盖式写入)
+	file, err := os.OpenFile(c.downloadTempPath(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	// 延迟关闭文件
+	defer file.Close()
+
+	// 写入初始化分段
+	if len(playlist.initURI) > 0 {
+		content, err := c.fetchHLSResource(ctx, playlist.initURI, playlist.initRange)
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(content); err != nil {
+			return err
+		}
+		c.tracker.add(int64(len(content)))
+	}
+
+	// 并发下载分段，按顺序写入
+	if err = c.fetchHLSSegments(ctx, playlist.segments, file); err != nil {
+		return err
+	}
+	c.tracker.complete()
+	// 同步到磁盘
+	if err = c.syncFile(file); err != nil {
+		return err
+	}
+	// 下载完成后校验文件内容
+	if hasher := c.newDownloadHasher(http.Header{}, false); hasher != nil {
+		file.Close()
+		if err = hasher.hashFile(c.downloadTempPath(), -1); err != nil {
+			return err
+		}
+		return c.verifyDownload(hasher)
+	}
+	return nil
+}
+
+// fetchHLSSegments 并发下载分段并按顺序写入
+// @params ctx      context.Context 下载上下文
+// @params segments []*hlsSegment   媒体分段
+// @params w        io.Writer       写入目标
+// @return          error           错误信息
+func (c *BeClient) fetchHLSSegments(ctx context.Context, segments []*hlsSegment, w io.Writer) error {
+	// 可取消全部分段的上下文
+	ctx, cancel := context.WithCancel(ctx)
+	// 返回前取消并等待全部下载协程结束，避免返回后继续使用客户端
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	// 下载结果
+	type result struct {
+		index   int
+		content []byte
+		err     error
+	}
+	results := make(chan result, len(segments))
+	// 同时下载及等待写入的分段数量不超过最大线程数
+	window := make(chan struct{}, c.downloadMaxThread)
+	// 按顺序分发下载任务
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range segments {
+			select {
+			case <-ctx.Done():
+				return
+			case window <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				content, err := c.fetchHLSSegment(ctx, segments[i])
+				results <- result{index: i, content: content, err: err}
+			}(i)
+		}
+	}()
+	// 按顺序写入
+	pending := make(map[int][]byte)
+	for next := 0; next < len(segments); {
+		var res result
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case res = <-results:
+		}
+		if res.err != nil {
+			return res.err
+		}
+		pending[res.index] = res.content
+		for content, ok := pending[next]; ok; content, ok = pending[next] {
+			if _, err := w.Write(content); err != nil {
+				return err
+			}
+			delete(pending, next)
+			<-window
+			next++
+			c.tracker.add(int64(len(content)))
+		}
+	}
+	return nil
+}
+
+// fetchHLSSegment 下载并解密一个分段
+// @params ctx     context.Context 下载上下文
+// @params segment *hlsSegment     媒体分段
+// @return         []byte          分段内容
+// @return         error           错误信息
+func (c *BeClient) fetchHLSSegment(ctx context.Context, segment *hlsSegment) ([]byte, error) {
+	// 下载分段
+	content, err := c.fetchHLSResource(ctx, segment.uri, segment.byteRange)
+	if err != nil {
+		return nil, err
+	}
+	// 未加密的分段
+	if segment.key == nil {
+		return content, nil
+	}
+	// 获取密钥
+	key, err := c.fetchHLSKey(ctx, segment.key.uri)
+	if err != nil {
+		return nil, err
+	}
+	// 未指定初始向量时使用分段序号
+	iv := segment.key.iv
+	if len(iv) == 0 {
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(segment.sequence))
+	}
+	return decryptAES128(content, key, iv)
+}
+
+// fetchHLSKey 获取AES-128密钥
+// @Desc 相同地址的密钥只会请求一次
+// @params ctx context.Context 下载上下文
+// @params uri string          密钥地址
+// @return     []byte          密钥
+// @return     error           错误信息
+func (c *BeClient) fetchHLSKey(ctx context.Context, uri string) ([]byte, error) {
+	val, _ := c.hlsKeys.LoadOrStore(uri, &hlsKeyEntry{})
+	entry := val.(*hlsKeyEntry)
+	entry.once.Do(func() {
+		entry.key, entry.err = c.fetchHLSResource(ctx, uri, nil)
+		if entry.err == nil && len(entry.key) != aes.BlockSize {
+			entry.err = fmt.Errorf("invalid hls key length %d", len(entry.key))
+		}
+	})
+	return entry.key, entry.err
+}
+
+// hlsKeyEntry 密钥缓存
+type hlsKeyEntry struct {
+	once sync.Once // 仅请求一次
+	key  []byte    // 密钥
+	err  error     // 错误信息
+}
+
+// fetchHLSResource 获取HLS资源内容
+// @Desc 指定了字节范围时发送Range请求，服务端忽略Range时从完整内容中截取
+// @params ctx       context.Context 下载上下文
+// @params uri       string          资源地址
+// @params byteRange *hlsByteRange   字节范围（为nil时为整个资源）
+// @return           []byte          资源内容
+// @return           error           错误信息
+func (c *BeClient) fetchHLSResource(ctx context.Context, uri string, byteRange *hlsByteRange) ([]byte, error) {
+	request, err := c.hlsRequest(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != nil {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", byteRange.offset, byteRange.offset+byteRange.length-1))
+	}
+	res, err := c.do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// 读取响应内容
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	// 整个资源
+	case byteRange == nil && res.StatusCode == http.StatusOK:
+		return content, nil
+
+	// 分段响应，需要校验返回的区域
+	case byteRange != nil && res.StatusCode == http.StatusPartialContent:
+		start, end, _, ok := parseContentRange(res.Header.Get("Content-Range"))
+		if !ok || start != byteRange.offset || end-start+1 != byteRange.length || int64(len(content)) != byteRange.length {
+			return nil, fmt.Errorf("unexpected Content-Range %q for hls byte range", res.Header.Get("Content-Range"))
+		}
+		return content, nil
+
+	// 服务端忽略了Range，从完整内容中截取
+	case byteRange != nil && res.StatusCode == http.StatusOK:
+		if int64(len(content)) < byteRange.offset+byteRange.length {
+			return nil, errors.New("hls byte range exceeds resource size")
+		}
+		return content[byteRange.offset : byteRange.offset+byteRange.length], nil
+	}
+	return nil, newStatusError(res, content)
+}
+
+// fetchHLSPlaylist 获取并解析播放列表
+// @params ctx     context.Context 下载上下文
+// @params request *http.Request   播放列表请求
+// @return         *hlsPlaylist    播放列表
+// @return         error           错误信息
+func (c *BeClient) fetchHLSPlaylist(ctx context.Context, request *http.Request) (*hlsPlaylist, error) {
+	request, err := rewindRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// 赋值response
+	c.response = res
+	// 读取响应内容
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, hlsMaxPlaylistSize))
+	if err != nil {
+		return nil, err
+	}
+	// 判断是否请求成功
+	if res.StatusCode != http.StatusOK {
+		return nil, newStatusError(res, content)
+	}
+	// 相对地址基于最终地址（重定向后）解析
+	return parseHLSPlaylist(content, res.Request.URL)
+}
+
+// hlsRequest 创建HLS资源请求
+// @Desc 拷贝原始请求的请求头及Cookie
+// @params ctx context.Context 下载上下文
+// @params uri string          资源地址（已解析为绝对地址）
+// @return     *http.Request   请求体
+// @return     error           错误信息
+func (c *BeClient) hlsRequest(ctx context.Context, uri string) (*http.Request, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	request := c.request.Clone(ctx)
+	request.Method = http.MethodGet
+	request.URL = u
+	request.Host = u.Host
+	request.Body = nil
+	request.GetBody = nil
+	request.ContentLength = 0
+	request.Header.Del("Range")
+	return request, nil
+}
+
+// parseHLSPlaylist 解析播放列表
+// @params content []byte       播放列表内容
+// @params baseURL *url.URL     播放列表地址
+// @return         *hlsPlaylist 播放列表
+// @return         error        错误信息
+func parseHLSPlaylist(content []byte, baseURL *url.URL) (*hlsPlaylist, error) {
+	// 兼容UTF-8 BOM
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), hlsMaxPlaylistSize)
+	// 校验文件头
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "#EXTM3U" {
+		return nil, errors.New("invalid hls playlist: missing #EXTM3U")
+	}
+	// 解析相对地址
+	resolve := func(uri string) (string, error) {
+		u, err := baseURL.Parse(uri)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+	playlist := new(hlsPlaylist)
+	var (
+		sequence    int64         // 当前分段序号
+		key         *hlsKey       // 当前加密信息
+		variant     *HLSVariant   // 等待地址的清晰度
+		segmentNext bool          // 下一个地址是否为分段
+		byteRange   *hlsByteRange // 下一个分段的字节范围
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0:
+			continue
+
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			variant = &HLSVariant{
+				Resolution: attrs["RESOLUTION"],
+				Codecs:     attrs["CODECS"],
+			}
+			variant.Bandwidth, _ = strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+			if index := strings.Index(variant.Resolution, "x"); index > 0 {
+				variant.Width, _ = strconv.ParseInt(variant.Resolution[:index], 10, 64)
+				variant.Height, _ = strconv.ParseInt(variant.Resolution[index+1:], 10, 64)
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			sequence, _ = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-KEY:"))
+			switch attrs["METHOD"] {
+			case "NONE":
+				key = nil
+			case "AES-128":
+				uri, err := resolve(attrs["URI"])
+				if err != nil {
+					return nil, err
+				}
+				key = &hlsKey{uri: uri}
+				if iv := attrs["IV"]; len(iv) > 0 {
+					iv = strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+					if key.iv, err = hex.DecodeString(iv); err != nil || len(key.iv) != aes.BlockSize {
+						return nil, fmt.Errorf("invalid hls key iv %q", attrs["IV"])
+					}
+				}
+			default:
+				return nil, fmt.Errorf("unsupported hls encryption method %q", attrs["METHOD"])
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := parseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))
+			uri, err := resolve(attrs["URI"])
+			if err != nil {
+				return nil, err
+			}
+			playlist.initURI = uri
+			// 初始化分段必须指定起始偏移量
+			if val, ok := attrs["BYTERANGE"]; ok {
+				if playlist.initRange, err = parseHLSByteRange(val); err != nil {
+					return nil, err
+				}
+				if playlist.initRange.offset < 0 {
+					return nil, fmt.Errorf("invalid hls map byte range %q", val)
+				}
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			var err error
+			if byteRange, err = parseHLSByteRange(strings.TrimPrefix(line, "#EXT-X-BYTERANGE:")); err != nil {
+				return nil, err
+			}
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			segmentNext = true
+
+		case strings.HasPrefix(line, "#"):
+			// 其他标签及注释忽略
+			continue
+
+		default:
+			uri, err := resolve(line)
+			if err != nil {
+				return nil, err
+			}
+			// 清晰度地址
+			if variant != nil {
+				variant.URI = uri
+				playlist.variants = append(playlist.variants, variant)
+				variant = nil
+				continue
+			}
+			// 分段地址
+			if segmentNext {
+				// 未指定起始偏移量时紧接上一个分段（需为同一资源）
+				if byteRange != nil && byteRange.offset < 0 {
+					var prev *hlsSegment
+					if len(playlist.segments) > 0 {
+						prev = playlist.segments[len(playlist.segments)-1]
+					}
+					if prev == nil || prev.uri != uri || prev.byteRange == nil {
+						return nil, fmt.Errorf("hls byte range without offset for %s", uri)
+					}
+					byteRange.offset = prev.byteRange.offset + prev.byteRange.length
+				}
+				playlist.segments = append(playlist.segments, &hlsSegment{
+					uri:       uri,
+					byteRange: byteRange,
+					sequence:  sequence,
+					key:       key,
+				})
+				sequence++
+				segmentNext = false
+				byteRange = nil
+			}
+		}
+	}
+	return playlist, scanner.Err()
+}
+
+// parseHLSByteRange 解析字节范围
+// @Desc 格式为<长度>[@<起始偏移量>]，未指定起始偏移量时offset为-1
+// @params val string        字节范围
+// @return     *hlsByteRange 字节范围
+// @return     error         错误信息
+func parseHLSByteRange(val string) (*hlsByteRange, error) {
+	byteRange := &hlsByteRange{offset: -1}
+	length, offset := strings.TrimSpace(val), ""
+	if index := strings.Index(length, "@"); index > -1 {
+		length, offset = length[:index], length[index+1:]
+	}
+	var err error
+	if byteRange.length, err = strconv.ParseInt(length, 10, 64); err != nil || byteRange.length <= 0 {
+		return nil, fmt.Errorf("invalid hls byte range %q", val)
+	}
+	if len(offset) > 0 {
+		if byteRange.offset, err = strconv.ParseInt(offset, 10, 64); err != nil || byteRange.offset < 0 {
+			return nil, fmt.Errorf("invalid hls byte range %q", val)
+		}
+	}
+	return byteRange, nil
+}
+
+// parseHLSAttributes 解析标签属性列表
+// @Desc 例如：BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+// @params val string            属性列表
+// @return     map[string]string 属性
+func parseHLSAttributes(val string) map[string]string {
+	attrs := make(map[string]string)
+	for len(val) > 0 {
+		// 截取属性名称
+		index := strings.Index(val, "=")
+		if index < 0 {
+			break
+		}
+		name := strings.TrimSpace(val[:index])
+		val = val[index+1:]
+		// 截取属性内容
+		var value string
+		if strings.HasPrefix(val, `"`) {
+			end := strings.Index(val[1:], `"`)
+			if end < 0 {
+				value, val = val[1:], ""
+			} else {
+				value, val = val[1:end+1], val[end+2:]
+			}
+		} else if end := strings.Index(val, ","); end >= 0 {
+			value, val = val[:end], val[end:]
+		} else {
+			value, val = val, ""
+		}
+		attrs[name] = value
+		val = strings.TrimPrefix(strings.TrimSpace(val), ",")
+	}
+	return attrs
+}
+
+// decryptAES128 解密AES-128（CBC、PKCS7填充）分段
+// @params content []byte 加密内容
+// @params key     []byte 密钥
+// @params iv      []byte 初始向量
+// @return         []byte 解密后的内容
+// @return         error  错误信息
+func decryptAES128(content, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 || len(content)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid hls encrypted segment length")
+	}
+	plain := make([]byte, len(content))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, content)
+	// 去除PKCS7填充
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plain) {
+		return nil, errors.New("invalid hls segment padding")
+	}
+	for _, b := range plain[len(plain)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid hls segment padding")
+		}
+	}
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,359 @@
+package beclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// downloadJobRecord 持久化的下载任务
+type downloadJobRecord struct {
+	URL      string `json:"url"`      // 下载地址
+	SavePath string `json:"savePath"` // 保存路径
+	Paused   bool   `json:"paused"`   // 是否已暂停
+}
+
+// NewDownloadManager 创建下载管理器
+// @Desc 任务使用断点续传下载，暂停及进程重启后从已下载的位置继续
+// @params concurrency int              同时下载的任务数量（小于1时为1）
+// @return             *DownloadManager 下载管理器指针
+func NewDownloadManager(concurrency int) *DownloadManager {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &DownloadManager{
+		concurrency: concurrency,
+		newRequest: func(url string) *BeClient {
+			return New(url)
+		},
+	}
+}
+
+// Request 配置创建下载请求的方法
+// @Desc 可用于配置请求头、重试策略、多线程参数等，管理器会在返回的请求上配置下载路径、断点续传、上下文及限速
+// @params fn func(url string) *BeClient 创建下载请求的方法
+// @return    *DownloadManager           下载管理器指针
+func (m *DownloadManager) Request(fn func(url string) *BeClient) *DownloadManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.newRequest = fn
+	return m
+}
+
+// RateLimit 限制全部任务的总下载速度
+// @params bytesPerSecond int64            每秒允许传输的字节数（小于等于0时不限速）
+// @return                *DownloadManager 下载管理器指针
+func (m *DownloadManager) RateLimit(bytesPerSecond int64) *DownloadManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if bytesPerSecond <= 0 {
+		m.limiter = nil
+		return m
+	}
+	m.limiter = NewRateLimiter(bytesPerSecond)
+	return m
+}
+
+// Persist 持久化下载队列
+// @Desc 加载文件中未完成的任务（已暂停的任务保持暂停），之后任务变化时会保存到该文件，进程重启后可继续下载
+// @params path string         队列文件路径
+// @return      []*DownloadJob 加载的任务
+// @return      error          错误信息
+func (m *DownloadManager) Persist(path string) ([]*DownloadJob, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.queuePath = path
+	// 读取队列文件
+	var records []downloadJobRecord
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(content) > 0 {
+		if err = json.Unmarshal(content, &records); err != nil {
+			return nil, err
+		}
+	}
+	// 恢复任务
+	jobs := make([]*DownloadJob, 0, len(records))
+	for _, record := range records {
+		job := m.newJob(record.URL, record.SavePath)
+		if record.Paused {
+			job.status = DownloadJobPaused
+		}
+		jobs = append(jobs, job)
+	}
+	m.schedule()
+	return jobs, m.save()
+}
+
+// Add 添加下载任务
+// @params url      string       下载地址
+// @params savePath string       保存路径
+// @return          *DownloadJob 下载任务
+func (m *DownloadManager) Add(url, savePath string) *DownloadJob {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job := m.newJob(url, savePath)
+	m.schedule()
+	_ = m.save()
+	return job
+}
+
+// Jobs 获取全部下载任务
+// @return []*DownloadJob 下载任务（按添加顺序）
+func (m *DownloadManager) Jobs() []*DownloadJob {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*DownloadJob(nil), m.jobs...)
+}
+
+// Stop 停止下载管理器
+// @Desc 中断下载中的任务并等待其结束，未完成的任务保留在队列文件中，进程重启后可通过Persist继续下载
+func (m *DownloadManager) Stop() {
+	m.mutex.Lock()
+	m.stopped = true
+	for _, job := range m.jobs {
+		if job.status == DownloadJobRunning {
+			job.status = DownloadJobQueued
+			job.cancel()
+		}
+	}
+	m.mutex.Unlock()
+	m.wg.Wait()
+}
+
+// newJob 创建下载任务并加入队列（调用方需持有锁）
+// @params url      string       下载地址
+// @params savePath string       保存路径
+// @return          *DownloadJob 下载任务
+func (m *DownloadManager) newJob(url, savePath string) *DownloadJob {
+	job := &DownloadJob{
+		manager:  m,
+		url:      url,
+		savePath: filepath.Join(strings.TrimSpace(savePath)),
+		status:   DownloadJobQueued,
+		done:     make(chan struct{}),
+	}
+	m.jobs = append(m.jobs, job)
+	return job
+}
+
+// schedule 按并发数量启动排队的任务（调用方需持有锁）
+func (m *DownloadManager) schedule() {
+	for _, job := range m.jobs {
+		if m.stopped || m.running >= m.concurrency {
+			return
+		}
+		// 暂停后尚未结束的下载协程结束后再启动
+		if job.status != DownloadJobQueued || job.active {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		job.status = DownloadJobRunning
+		job.active = true
+		job.cancel = cancel
+		m.running++
+		m.wg.Add(1)
+		go m.run(ctx, job)
+	}
+}
+
+// request 创建任务的下载请求
+// @params ctx context.Context 下载上下文
+// @params job *DownloadJob    下载任务
+// @return     *BeClient       下载请求
+func (m *DownloadManager) request(ctx context.Context, job *DownloadJob) *BeClient {
+	m.mutex.Lock()
+	newRequest, limiter := m.newRequest, m.limiter
+	m.mutex.Unlock()
+	r := newRequest(job.url).
+		WithContext(ctx).
+		Download(job.savePath, nil).
+		DownloadResume()
+	// 记录进度的同时保留请求中配置的进度事件回调
+	callback := r.downloadProgressFunc
+	r.DownloadEvents(r.downloadProgressInterval, func(progress *DownloadProgress) {
+		job.setProgress(progress)
+		if callback != nil {
+			callback(progress)
+		}
+	})
+	if limiter != nil {
+		r.Limiter(limiter)
+	}
+	return r
+}
+
+// run 执行下载任务
+// @params ctx context.Context 下载上下文
+// @params job *DownloadJob    下载任务
+func (m *DownloadManager) run(ctx context.Context, job *DownloadJob) {
+	defer m.wg.Done()
+	r := m.request(ctx, job)
+	err := r.Get(nil)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job.cancel()
+	job.active = false
+	m.running--
+	switch {
+	case job.status == DownloadJobCanceled:
+		// 已取消，删除未完成的文件
+		removePartial(r)
+	case err == nil:
+		// 下载完成（暂停前已经完成时同样视为完成）
+		job.status = DownloadJobCompleted
+		close(job.done)
+	case job.status == DownloadJobRunning:
+		// 下载失败
+		job.err = err
+		job.status = DownloadJobFailed
+		close(job.done)
+	}
+	// 已暂停或管理器已停止时保留未完成的文件
+	m.schedule()
+	_ = m.save()
+}
+
+// save 保存下载队列（调用方需持有锁）
+// @return error 错误信息
+func (m *DownloadManager) save() error {
+	if len(m.queuePath) == 0 {
+		return nil
+	}
+	records := make([]downloadJobRecord, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		switch job.status {
+		case DownloadJobQueued, DownloadJobRunning, DownloadJobPaused:
+			records = append(records, downloadJobRecord{
+				URL:      job.url,
+				SavePath: job.savePath,
+				Paused:   job.status == DownloadJobPaused,
+			})
+		}
+	}
+	content, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	// 写入临时文件后重命名
+	tmpPath := m.queuePath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, content, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, m.queuePath)
+}
+
+// removePartial 删除未完成的临时文件及断点续传状态
+// @params r *BeClient 下载请求
+func removePartial(r *BeClient) {
+	_ = os.Remove(r.downloadTempPath())
+	_ = removeDownloadState(r.downloadStatePath())
+}
+
+// URL 获取下载地址
+// @return string 下载地址
+func (j *DownloadJob) URL() string {
+	return j.url
+}
+
+// SavePath 获取保存路径
+// @return string 保存路径
+func (j *DownloadJob) SavePath() string {
+	return j.savePath
+}
+
+// Status 获取任务状态
+// @return DownloadJobStatus 任务状态
+func (j *DownloadJob) Status() DownloadJobStatus {
+	j.manager.mutex.Lock()
+	defer j.manager.mutex.Unlock()
+	return j.status
+}
+
+// Progress 获取最近一次下载进度
+// @return *DownloadProgress 下载进度（尚未开始下载时为nil）
+func (j *DownloadJob) Progress() *DownloadProgress {
+	j.manager.mutex.Lock()
+	defer j.manager.mutex.Unlock()
+	return j.progress
+}
+
+// setProgress 记录下载进度
+// @params progress *DownloadProgress 下载进度
+func (j *DownloadJob) setProgress(progress *DownloadProgress) {
+	j.manager.mutex.Lock()
+	defer j.manager.mutex.Unlock()
+	j.progress = progress
+}
+
+// Pause 暂停任务
+// @Desc 下载中的任务会中断并保留已下载的内容，恢复后从已下载的位置继续
+func (j *DownloadJob) Pause() {
+	m := j.manager
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	switch j.status {
+	case DownloadJobRunning:
+		j.cancel()
+	case DownloadJobQueued:
+	default:
+		return
+	}
+	j.status = DownloadJobPaused
+	_ = m.save()
+}
+
+// Resume 恢复已暂停的任务
+// @Desc 任务重新进入队列，按并发数量开始下载
+func (j *DownloadJob) Resume() {
+	m := j.manager
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if j.status != DownloadJobPaused {
+		return
+	}
+	j.status = DownloadJobQueued
+	m.schedule()
+	_ = m.save()
+}
+
+// Cancel 取消任务
+// @Desc 删除已下载的内容，Wait返回ErrDownloadCanceled
+func (j *DownloadJob) Cancel() {
+	m := j.manager
+	m.mutex.Lock()
+	switch j.status {
+	case DownloadJobCompleted, DownloadJobFailed, DownloadJobCanceled:
+		m.mutex.Unlock()
+		return
+	}
+	j.status = DownloadJobCanceled
+	j.err = ErrDownloadCanceled
+	close(j.done)
+	// 下载中的任务在下载协程结束后删除未完成的文件
+	active, newRequest := j.active, m.newRequest
+	if active {
+		j.cancel()
+	}
+	_ = m.save()
+	m.mutex.Unlock()
+	// 创建请求的方法为用户代码，不能在持有锁时调用
+	if !active {
+		removePartial(newRequest(j.url).Download(j.savePath, nil))
+	}
+}
+
+// Wait 等待任务结束（完成、失败或取消）
+// @Desc 暂停的任务不会结束，需要恢复或取消
+// @return error 下载结果（取消时为ErrDownloadCanceled）
+func (j *DownloadJob) Wait() error {
+	<-j.done
+	j.manager.mutex.Lock()
+	defer j.manager.mutex.Unlock()
+	return j.err
+}
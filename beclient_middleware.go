@@ -0,0 +1,68 @@
+package beclient
+
+import "net/http"
+
+// Use 注册客户端中间件
+// @Desc 按注册顺序由外向内执行，通过R()创建的请求会继承已注册的中间件
+// @params middlewares ...Middleware 中间件
+// @return             *Client       可复用客户端指针
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// Use 注册请求中间件
+// @Desc 按注册顺序由外向内执行，位于客户端中间件之内，作用于本次请求发出的每一个HTTP请求（包括重试及多线程下载的每个分段）
+// @params middlewares ...Middleware 中间件
+// @return             *BeClient     客户端指针
+func (c *BeClient) Use(middlewares ...Middleware) *BeClient {
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// BeforeRequest 创建请求前置中间件
+// @Desc 可在请求发出前修改请求体，返回错误时将中止请求
+// @params fn func(*http.Request) error 前置处理函数
+// @return    Middleware                中间件
+func BeforeRequest(fn func(request *http.Request) error) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(request *http.Request) (*http.Response, error) {
+			if err := fn(request); err != nil {
+				return nil, err
+			}
+			return next(request)
+		}
+	}
+}
+
+// AfterResponse 创建响应后置中间件
+// @Desc 可检查或替换响应体及错误信息
+// @params fn func(*http.Response, error) (*http.Response, error) 后置处理函数
+// @return    Middleware                                          中间件
+func AfterResponse(fn func(response *http.Response, err error) (*http.Response, error)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(request *http.Request) (*http.Response, error) {
+			return fn(next(request))
+		}
+	}
+}
+
+// roundTrip 经过中间件链发送请求
+// @params request *http.Request  请求体
+// @return         *http.Response 响应体
+// @return         error          错误信息
+func (c *BeClient) roundTrip(request *http.Request) (*http.Response, error) {
+	// 最内层为真正发送请求的HTTP客户端
+	next := RoundTripFunc(c.client.Do)
+	// 限速位于中间件之内，作用于实际传输的请求体及响应体
+	if c.rateLimiter != nil {
+		next = c.rateLimiter.wrap(next)
+	}
+	// 由内向外包装中间件
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		next = c.middlewares[i](next)
+	}
+	return next(request)
+}
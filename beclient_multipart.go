@@ -0,0 +1,198 @@
+package beclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ajg/form"
+)
+
+// formPart multipart/form-data表单中的单个分段
+type formPart struct {
+	fieldName string               // 字段名称
+	fileName  string               // 文件名称（为空时表示普通文本字段）
+	filePath  string               // 文件路径（从磁盘读取文件内容）
+	reader    io.Reader            // 文件内容读取器（仅会被读取一次）
+	content   []byte               // 已缓存的分段内容
+	header    textproto.MIMEHeader // 自定义分段头
+}
+
+// quoteEscaper 分段头中文件名的转义器
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// FormField 添加multipart/form-data表单文本字段
+// @Desc 调用后资源类型将自动切换为multipart/form-data，多次调用相同KEY会追加多个字段
+// @params key string    字段名称
+// @params val string    字段内容
+// @return     *BeClient 客户端指针
+func (c *BeClient) FormField(key, val string) *BeClient {
+	c.contentType = ContentTypeFormBody
+	c.formParts = append(c.formParts, &formPart{
+		fieldName: key,
+		content:   []byte(val),
+	})
+	return c
+}
+
+// FormFile 添加multipart/form-data表单文件（从读取器中读取）
+// @Desc 调用后资源类型将自动切换为multipart/form-data，读取器仅会被读取一次，内容会被缓存以便重复构建请求体
+// @params field    string                  字段名称
+// @params filename string                  文件名称
+// @params reader   io.Reader               文件内容读取器
+// @params header   ...textproto.MIMEHeader 自定义分段头（可覆盖默认的Content-Disposition及Content-Type）
+// @return          *BeClient               客户端指针
+func (c *BeClient) FormFile(field, filename string, reader io.Reader, header ...textproto.MIMEHeader) *BeClient {
+	// 读取器不能为空
+	if reader == nil {
+		c.errMsg = errors.New("form file reader is nil")
+		return c
+	}
+	c.contentType = ContentTypeFormBody
+	part := &formPart{
+		fieldName: field,
+		fileName:  filename,
+		reader:    reader,
+	}
+	if len(header) > 0 {
+		part.header = header[0]
+	}
+	c.formParts = append(c.formParts, part)
+	return c
+}
+
+// FormFilePath 添加multipart/form-data表单文件（从磁盘路径读取）
+// @Desc 调用后资源类型将自动切换为multipart/form-data，文件名称默认取路径的最后一部分
+// @params field    string                  字段名称
+// @params filePath string                  文件路径
+// @params header   ...textproto.MIMEHeader 自定义分段头（可覆盖默认的Content-Disposition及Content-Type）
+// @return          *BeClient               客户端指针
+func (c *BeClient) FormFilePath(field, filePath string, header ...textproto.MIMEHeader) *BeClient {
+	c.contentType = ContentTypeFormBody
+	part := &formPart{
+		fieldName: field,
+		fileName:  filepath.Base(filePath),
+		filePath:  filePath,
+	}
+	if len(header) > 0 {
+		part.header = header[0]
+	}
+	c.formParts = append(c.formParts, part)
+	return c
+}
+
+// multipartBoundary 获取multipart/form-data分隔符
+// @Desc 分隔符仅生成一次，保证多次构建请求体时与请求头保持一致
+// @return string 分隔符
+func (c *BeClient) multipartBoundary() string {
+	if len(c.formBoundary) == 0 {
+		c.formBoundary = multipart.NewWriter(ioutil.Discard).Boundary()
+	}
+	return c.formBoundary
+}
+
+// multipartContentType 获取multipart/form-data请求头内容
+// @return string 携带分隔符的Content-Type
+func (c *BeClient) multipartContentType() string {
+	return fmt.Sprintf("%s; boundary=%s", ContentTypeFormBody, c.multipartBoundary())
+}
+
+// multipartEncode 将请求参数及表单分段编码为multipart/form-data请求体
+// @return []byte 编码后的请求体
+// @return error  错误信息
+func (c *BeClient) multipartEncode() ([]byte, error) {
+	// 初始化写入器
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
+	if err := writer.SetBoundary(c.multipartBoundary()); err != nil {
+		return nil, err
+	}
+	// 先写入请求参数中的字段
+	if c.data != nil {
+		values, err := form.EncodeToValues(c.data)
+		if err != nil {
+			return nil, err
+		}
+		// 排序后写入，保证请求体稳定
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, val := range values[key] {
+				if err := writer.WriteField(key, val); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	// 再写入自定义分段
+	for _, part := range c.formParts {
+		// 读取分段内容
+		content, err := part.read()
+		if err != nil {
+			return nil, err
+		}
+		// 创建分段
+		w, err := writer.CreatePart(part.mimeHeader())
+		if err != nil {
+			return nil, err
+		}
+		// 写入分段内容
+		if _, err = w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	// 写入结束分隔符
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// read 读取分段内容
+// @return []byte 分段内容
+// @return error  错误信息
+func (p *formPart) read() ([]byte, error) {
+	// 从磁盘读取的文件每次都重新读取
+	if len(p.filePath) > 0 {
+		return ioutil.ReadFile(p.filePath)
+	}
+	// 读取器仅能读取一次，读取后缓存
+	if p.reader != nil {
+		content, err := ioutil.ReadAll(p.reader)
+		if err != nil {
+			return nil, err
+		}
+		p.content = content
+		p.reader = nil
+	}
+	return p.content, nil
+}
+
+// mimeHeader 生成分段头
+// @return textproto.MIMEHeader 分段头
+func (p *formPart) mimeHeader() textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	// 生成默认分段头
+	if len(p.fileName) > 0 || len(p.filePath) > 0 {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(p.fieldName), quoteEscaper.Replace(p.fileName)))
+		header.Set("Content-Type", "application/octet-stream")
+	} else {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.fieldName)))
+	}
+	// 自定义分段头覆盖默认值
+	for key, vals := range p.header {
+		header[textproto.CanonicalMIMEHeaderKey(key)] = vals
+	}
+	return header
+}
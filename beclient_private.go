@@ -1,65 +1,68 @@
 package beclient
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strings"
-	"sync"
-	"sync/atomic"
-
-	"github.com/ajg/form"
 )
 
 // build 构建HTTP客户端和HTTP请求体
 func (c *BeClient) build() error {
-	// 初始化客户端
-	client := &http.Client{
-		Transport:     http.DefaultClient.Transport,
-		CheckRedirect: http.DefaultClient.CheckRedirect, // 检查重定向
-		Jar:           http.DefaultClient.Jar,
-		Timeout:       c.timeOut,
-	}
-	// 转换请求参数
-	reqBody, err := c.requestConvertData()
+	// 初始化客户端（浅拷贝共享的HTTP客户端，Transport共用以复用连接）
+	client := *c.httpClient
+	client.Timeout = c.timeOut
+	// 获取请求体
+	reqBody, getBody, size, err := c.requestBody()
 	if err != nil {
 		return err
 	}
 	// 先拼接URL参数
+	reqURL := c.baseURL + c.pathURL
 	if len(c.querys) > 0 {
 		// 判断是否禁用了地址解析
 		if c.disabledBaseURLParse {
 			if strings.Contains(c.baseURL, "?") {
-				c.baseURL += fmt.Sprintf("&%s", c.querys.Encode())
+				reqURL += fmt.Sprintf("&%s", c.querys.Encode())
 			} else {
-				c.baseURL += fmt.Sprintf("?%s", c.querys.Encode())
+				reqURL += fmt.Sprintf("?%s", c.querys.Encode())
 			}
 		} else {
 			if strings.Contains(c.pathURL, "?") || strings.Contains(c.baseURL, "?") {
-				c.pathURL += fmt.Sprintf("&%s", c.querys.Encode())
+				reqURL += fmt.Sprintf("&%s", c.querys.Encode())
 			} else {
-				c.pathURL += fmt.Sprintf("?%s", c.querys.Encode())
+				reqURL += fmt.Sprintf("?%s", c.querys.Encode())
 			}
 		}
 	}
 	// 创建请求体
-	request, err := http.NewRequest(string(c.method), c.baseURL+c.pathURL, bytes.NewReader(reqBody))
+	request, err := http.NewRequestWithContext(c.context(), string(c.method), reqURL, reqBody)
 	if err != nil {
+		if closer, ok := reqBody.(io.Closer); ok && getBody != nil {
+			closer.Close()
+		}
 		return err
 	}
+	// 流式请求体
+	if getBody != nil {
+		request.GetBody = getBody
+	}
+	if size >= 0 {
+		request.ContentLength = size
+	}
 	// 配置请求头
 	c.headers.Range(func(key, val interface{}) bool {
 		request.Header.Set(key.(string), val.(string))
 		return true
 	})
+	// multipart/form-data需要携带分隔符（覆盖自定义的Content-Type，否则请求体无法解析）
+	if c.contentType == ContentTypeFormBody {
+		request.Header.Set("Content-Type", c.multipartContentType())
+	}
 	// 配置Cookie
 	c.cookies.Range(func(key, val interface{}) bool {
 		request.AddCookie(&http.Cookie{
@@ -69,12 +72,35 @@ func (c *BeClient) build() error {
 		return true
 	})
 	// 标记已经构建完成
-	c.client = client
+	c.client = &client
 	c.request = request
+	// 上传进度
+	c.trackUpload()
 	// 返回空错误
 	return nil
 }
 
+// context 获取请求上下文
+// @return context.Context 请求上下文（未配置时为context.Background()）
+func (c *BeClient) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// withTimeout 基于超时时间派生可取消上下文
+// @Desc 超时时间小于等于0时不设置截止时间
+// @params ctx    context.Context    父级上下文
+// @return        context.Context    派生的上下文
+// @return        context.CancelFunc 取消函数
+func (c *BeClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeOut > 0 {
+		return context.WithTimeout(ctx, c.timeOut)
+	}
+	return context.WithCancel(ctx)
+}
+
 // send 发送请求
 // @Desc 任何请求均会通过该接口发出请求
 // @return []byte 响应体Body内容
@@ -84,9 +110,9 @@ func (c *BeClient) send(resData interface{}, resContentType ...ContentTypeType)
 	defer func() {
 		// 是否需要打印Debug
 		if c.debug {
-			fmt.Printf("\n\nBeClient: %+v\n", *c)
-			fmt.Printf("\nRequest: %+v\n", *c.request)
-			fmt.Printf("\nResponse: %+v\n\n", *c.response)
+			fmt.Printf("\n\nBeClient: %+v\n", c)
+			fmt.Printf("\nRequest: %+v\n", c.request)
+			fmt.Printf("\nResponse: %+v\n\n", c.response)
 		}
 	}()
 	// 是否有全局异常
@@ -108,7 +134,7 @@ func (c *BeClient) send(resData interface{}, resContentType ...ContentTypeType)
 		return c.download()
 	}
 	// 普通请求,发起请求
-	res, err := c.client.Do(c.request)
+	res, err := c.do(c.request)
 	// 判断是否请求错误
 	if err != nil {
 		return err
@@ -125,309 +151,43 @@ func (c *BeClient) send(resData interface{}, resContentType ...ContentTypeType)
 	if err != nil {
 		return err
 	}
-	// 转换响应内容，结束请求
-	return c.responseConvertData(resBody, resData, resContentType...)
-}
-
-// download 下载文件
-func (c *BeClient) download() error {
-	// 是否存在下载地址
-	if len(c.downloadSavePath) == 0 {
-		// 没有保存路径，直接报错
-		return errors.New("download file save path is null")
-	}
-	// 判断文件夹部分是否为空
-	saveDir := filepath.Dir(c.downloadSavePath)
-	if len(saveDir) == 0 {
-		return errors.New("download file save path dir is nil")
-	}
-	// 创建文件夹部分
-	err := os.MkdirAll(saveDir, 0755)
-	if err != nil {
-		return err
-	}
-	// 缓存请求类型
-	method := c.request.Method
-	// 发送HEAD请求
-	c.request.Method = string(MethodHead)
-	headRes, err := c.client.Do(c.request)
-	// 还原请求类型
-	c.request.Method = method
-	// 判断是否请求成功
-	if err != nil {
-		// 直接走单线程下载
-		return c.singleThreadDownload()
-	}
-	if headRes == nil {
-		// 直接走单线程下载
-		return c.singleThreadDownload()
-	}
-	// 结束时释放
-	defer headRes.Body.Close()
-	// 判断是否请求成功
-	if headRes.StatusCode != http.StatusOK {
-		// 直接走单线程下载
-		return c.singleThreadDownload()
-	}
-	// 判断大小是否小于缓冲区
-	if headRes.ContentLength <= c.downloadBufferSize {
-		// 直接走单线程下载
-		return c.singleThreadDownload()
-	}
-	// 判断是否支持分片下载
-	if !strings.Contains(headRes.Header.Get("Accept-Ranges"), "bytes") {
-		// 直接走单线程下载
-		return c.singleThreadDownload()
-	}
-	// 走多线程下载
-	return c.multiThreadDownload(headRes)
-}
-
-// singleThreadDownload 单线程下载
-func (c *BeClient) singleThreadDownload() error {
-	// 发送请求
-	res, err := c.client.Do(c.request)
-	if err != nil {
-		return err
-	}
-	// 判断响应是否为空
-	if res == nil {
-		return errors.New("response is nil pointer address")
-	}
-	// 结束时释放
-	defer res.Body.Close()
-	// 赋值response
-	c.response = res
-	// 判断是否请求成功
-	if res.StatusCode != http.StatusOK {
-		// 将返回的错误信息读出
-		errBody, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return err
-		}
-		return errors.New(string(errBody))
-	}
-
-	// 打开文件，边下载边写入，防止内存占用高(os.O_TRUNC覆盖式写入)
-	file, err := os.OpenFile(c.downloadSavePath, os.O_CREATE|os.O_WRONLY|os.O_SYNC|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
-	}
-	// 延迟关闭文件
-	defer file.Close()
-
-	// 定义下载缓冲区
-	downBuffer := make([]byte, c.downloadBufferSize)
-	// 已下载的大小
-	var currSize int64
-	// 读取响应
-	for currSize < res.ContentLength {
-		// 读取响应体
-		size, err := res.Body.Read(downBuffer)
-		// 追加已下载大小
-		currSize += int64(size)
-		// 判断是否发生错误
-		if err != nil && err != io.EOF {
-			// 有错误，直接返回错误
-			return err
-		}
-		// 写入到文件
-		n, err := file.Write(downBuffer[:size])
-		// 判断是否写入正确
-		if err != nil {
-			return err
-		}
-		if n != size {
-			return errors.New("write to file byte length inconsistency")
-		}
-		// 判断是否需要回调
-		if c.downloadCallFunc != nil {
-			// 回调进度
-			c.downloadCallFunc(float64(currSize), float64(res.ContentLength))
-		}
-	}
-	// 下载成功
-	return nil
-}
-
-// multiThreadDownload 多线程下载
-func (c *BeClient) multiThreadDownload(headRes *http.Response) error {
-	// 根据大小计算需要的线程数
-	var downloadThreadNum = headRes.ContentLength / c.downloadMaxSize
-	// 有余数时需要加一个线程
-	if headRes.ContentLength%c.downloadMaxSize != 0 {
-		downloadThreadNum++
-	}
-	// 是否超过最大线程限制
-	if downloadThreadNum > c.downloadMaxThread {
-		// 重置为最大线程数
-		downloadThreadNum = c.downloadMaxThread
-	}
-	// 根据计算出的下载线程数量计算每个线程的下载容量
-	var singleDownloadSize = headRes.ContentLength / downloadThreadNum
-	// 计算最后一个线程的下载量
-	var lastThreadDownloadContent = singleDownloadSize + (headRes.ContentLength % downloadThreadNum)
-
-	// 打开文件，边下载边写入，防止内存占用高(os.O_TRUNC覆盖式写入)
-	file, err := os.OpenFile(c.downloadSavePath, os.O_CREATE|os.O_WRONLY|os.O_SYNC|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
-	}
-	// 延迟关闭文件
-	defer file.Close()
-
-	// 初始化可取消上下文
-	globalCtx, globalCancel := context.WithTimeout(context.Background(), c.timeOut)
-	// 初始化等待组
-	var wg sync.WaitGroup
-
-	// 已下载总量
-	var downloadedSize int64
-
-	// 遍历线程数
-	var i int64
-	for i = 0; i < downloadThreadNum; i++ {
-		// 计算下载偏移量Range为闭区间
-		offsetStart := i * singleDownloadSize
-		offsetEnd := (i+1)*singleDownloadSize - 1
-		// 是否为最后一个线程
-		if i+1 == downloadThreadNum {
-			offsetEnd = (offsetStart + lastThreadDownloadContent) - 1
-		}
-		// 开启协程下载
-		wg.Add(1)
-		go func(start, end int64) {
-			defer wg.Done()
-			// 拷贝request
-			request := c.request.Clone(context.Background())
-			// 请求Body需要单独拷贝
-			body, err := c.requestConvertData()
-			if err != nil {
-				c.errMsg = err // 错误赋值到全局
-				globalCancel() // 取消全部线程的下载
-				return
-			}
-			request.Body = ioutil.NopCloser(bytes.NewReader(body))
-			// 配置分段区域
-			request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
-			// 发送请求
-			res, err := c.client.Do(request)
-			if err != nil {
-				c.errMsg = err // 错误赋值到全局
-				globalCancel() // 取消全部线程的下载
-				return
-			}
-			// 判断响应是否为空
-			if res == nil {
-				c.errMsg = errors.New("response is nil pointer address") // 错误赋值到全局
-				globalCancel()                                           // 取消全部线程的下载
-				return
-			}
-			// 结束时释放
-			defer res.Body.Close()
-			// 判断是否需要赋值response(至于赋值第几个response并不需要关心)
-			if c.response == nil {
-				// 赋值response
-				c.response = res
-			}
-			// 判断是否请求成功
-			if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
-				// 将返回的错误信息读出
-				errBody, err := ioutil.ReadAll(res.Body)
-				if err != nil {
-					c.errMsg = err // 错误赋值到全局
-				} else {
-					c.errMsg = errors.New(string(errBody)) // 错误赋值到全局
-				}
-				globalCancel() // 取消全部线程的下载
-				return
-			}
-
-			// 定义下载缓冲区
-			downBuffer := make([]byte, c.downloadBufferSize)
-			// 读取响应
-			for start < end {
-				select {
-				case <-globalCtx.Done():
-					return
-
-				default:
-					// 读取响应体
-					size, err := res.Body.Read(downBuffer)
-					// 判断是否发生错误
-					if err != nil && err != io.EOF {
-						c.errMsg = err // 错误赋值到全局
-						globalCancel() // 取消全部线程的下载
-						return
-					}
-					// 写入到文件
-					n, err := file.WriteAt(downBuffer[:size], start)
-					// 追加已下载大小
-					start += int64(size)
-					// 赋值到全局总量
-					atomic.AddInt64(&downloadedSize, int64(size))
-					// 判断是否写入正确
-					if err != nil {
-						c.errMsg = err // 错误赋值到全局
-						globalCancel() // 取消全部线程的下载
-						return
-					}
-					if n != size {
-						c.errMsg = errors.New("write to file byte length inconsistency") // 错误赋值到全局
-						globalCancel()                                                   // 取消全部线程的下载
-						return
-					}
-					// 判断是否需要回调
-					if c.downloadCallFunc != nil {
-						// 回调进度
-						c.downloadCallFunc(float64(atomic.LoadInt64(&downloadedSize)), float64(headRes.ContentLength))
-					}
-				}
-			}
-		}(offsetStart, offsetEnd)
-	}
-	// 等待全部线程下载完成
-	wg.Wait()
-	// 上下文结束
-	globalCancel()
-	// 判断是否有错误信息
-	if c.errMsg != nil {
-		// 下载失败
-		return c.errMsg
-	}
-	if globalCtx.Err() != nil && globalCtx.Err() != context.Canceled {
-		return globalCtx.Err()
+	// 判断是否需要将非2xx响应视为错误
+	if c.statusCheck && !isSuccessStatus(res.StatusCode) {
+		return c.statusError(res, resBody)
 	}
-	// 下载成功
-	return nil
+	// 转换响应内容，结束请求
+	return c.responseConvertData(res.Header, resBody, resData, resContentType...)
 }
 
 // requestConvertData 根据请求资源类型转换请求数据
-// @params data        interface{}     请求参数
-// @params contentType ContentTypeType 资源类型
+// @Desc 除multipart/form-data外均通过资源类型对应的编解码器编码
 // @return reqBody     []byte          处理后的请求参数
 // @return err         error           错误信息
 func (c *BeClient) requestConvertData() (reqBody []byte, err error) {
 	// 判断请求参数是否为空
-	if c.data == nil {
+	if c.data == nil && len(c.formParts) == 0 {
 		return nil, nil
 	}
 
-	// 根据资源类型处理请求参数
-	switch c.contentType {
-
-	// JSON数据
-	case ContentTypeJson:
-		return json.Marshal(c.data)
+	// 表单数据
+	if c.contentType == ContentTypeFormBody {
+		return c.multipartEncode()
+	}
 
-	// XML类型数据
-	case ContentTypeTextXml, ContentTypeAppXml:
-		return xml.Marshal(c.data)
+	// 查找编解码器
+	codec, ok := c.codec(c.contentType)
+	if !ok {
+		return nil, errors.New("unsupported resource type")
+	}
+	reqBody, err = codec.Marshal(c.data)
+	if err != nil {
+		return nil, err
+	}
 
 	// URL后追加参数
-	case ContentTypeFormURL:
+	if c.contentType == ContentTypeFormURL {
 		// 解析参数
-		values, err := form.EncodeToValues(c.data)
+		values, err := url.ParseQuery(string(reqBody))
 		if err != nil {
 			return nil, err
 		}
@@ -436,27 +196,19 @@ func (c *BeClient) requestConvertData() (reqBody []byte, err error) {
 			c.Query(key, values.Get(key))
 		}
 		return nil, nil
-
-	// 表单数据
-	case ContentTypeFormBody:
-		// 解析参数
-		values, err := form.EncodeToValues(c.data)
-		if err != nil {
-			return nil, err
-		}
-		return []byte(values.Encode()), nil
-
 	}
-
-	return nil, errors.New("unsupported resource type")
+	return reqBody, nil
 }
 
-// requestConvertData 根据响应资源类型转换响应数据
-// @params src         []byte          响应内容
-// @params dst         interface{}    用户自定义变量地址
-// @params contentType ContentTypeType 资源类型
-// @return err         error           错误信息
-func (c *BeClient) responseConvertData(src []byte, dst interface{}, resContentType ...ContentTypeType) error {
+// responseConvertData 根据响应资源类型转换响应数据
+// @Desc 未指定资源类型或指定为ContentTypeAuto时，*[]byte、*string及io.Writer（例如*bytes.Buffer）直接接收原始内容，
+// 其他类型根据响应头的Content-Type（支持+json、+xml结构化后缀）查找编解码器
+// @params header         http.Header        响应头
+// @params src            []byte             响应内容
+// @params dst            interface{}        用户自定义变量地址
+// @params resContentType ...ContentTypeType 资源类型
+// @return err            error              错误信息
+func (c *BeClient) responseConvertData(header http.Header, src []byte, dst interface{}, resContentType ...ContentTypeType) error {
 	// 判断响应内容是否为空
 	if len(src) == 0 {
 		return nil
@@ -467,30 +219,28 @@ func (c *BeClient) responseConvertData(src []byte, dst interface{}, resContentTy
 		return nil
 	}
 
-	// 判断是否需要对响应内容做转换
-	if len(resContentType) > 0 { // 需要转换
-		// 取出资源类型
-		contentType := resContentType[0]
-		// 根据资源类型处理响应内容
-		switch contentType {
-		// JSON数据
-		case ContentTypeJson:
-			return json.Unmarshal(src, dst)
-		// XML类型数据
-		case ContentTypeTextXml, ContentTypeAppXml:
-			return xml.Unmarshal(src, dst)
-		// 表单数据
-		case ContentTypeFormBody:
-			// 解析参数
-			return form.DecodeString(dst, string(src))
+	// 判断是否指定了资源类型
+	if len(resContentType) > 0 && resContentType[0] != ContentTypeAuto {
+		// 根据资源类型查找编解码器
+		if codec, ok := c.codec(resContentType[0]); ok {
+			return codec.Unmarshal(src, dst)
 		}
+		// 没有对应的编解码器时直接赋值
+		if ok, err := rawConvertData(src, dst); ok {
+			return err
+		}
+		return errors.New("dst is not *[]byte, *string or io.Writer")
 	}
-	// 默认不转换，直接赋值
-	dstValue, ok := dst.(*[]byte)
-	if ok {
-		*dstValue = src
-		return nil
+
+	// 自动模式，接收原始内容的变量直接赋值
+	if ok, err := rawConvertData(src, dst); ok {
+		return err
+	}
+	// 根据响应头查找编解码器
+	contentType := header.Get("Content-Type")
+	if codec, ok := c.responseCodec(contentType); ok {
+		return codec.Unmarshal(src, dst)
 	}
 	// 结束
-	return errors.New("dst is not *[]byte")
+	return fmt.Errorf("unsupported response content type %q", contentType)
 }
@@ -1,8 +1,8 @@
 package beclient
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"net/url"
 	"path/filepath"
@@ -11,46 +11,12 @@ import (
 )
 
 // New 创建一个基础客户端
+// @Desc 每次调用都会创建一个新的请求，需要复用配置及连接时请使用NewClient(...).R()
 // @params baseURL              string    基础访问地址
 // @params disabledBaseURLParse ...bool   是否禁用基础地址解析
 // @return                      *BeClient 客户端指针
 func New(baseURL string, disabledBaseURLParse ...bool) *BeClient {
-	// 新建客户端
-	c := new(BeClient)
-	// 初始化query参数
-	c.querys = make(url.Values)
-	// 初始化默认下载缓冲区
-	c.DownloadBufferSize(1024 * 1024 * 5)
-	// 配置多线程下载参数
-	c.DownloadMultiThread(20, 1024*1024*100)
-	// 初始化默认超时时间为15秒
-	c.TimeOut(time.Second * 15)
-	// 初始化默认资源类型
-	c.ContentType(ContentTypeJson)
-	// 是否禁用地址解析
-	if len(disabledBaseURLParse) > 0 && disabledBaseURLParse[0] {
-		c.disabledBaseURLParse = true
-		c.baseURL = baseURL
-		return c
-	}
-	// 解析基本网址
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		c.errMsg = err
-		return c
-	}
-	// 截取协议，域名或IP，端口号三部分
-	c.baseURL = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
-	// 判断是否需要赋值path
-	if len(u.Path) > 0 {
-		c.Path(u.Path)
-	}
-	// 判断是否需要处理URL参数
-	for key := range u.Query() {
-		c.Query(key, u.Query().Get(key))
-	}
-	// 返回创建的客户端
-	return c
+	return NewClient(baseURL, disabledBaseURLParse...).R()
 }
 
 // Path 路由地址
@@ -144,8 +110,22 @@ func (c *BeClient) TimeOut(timeOut time.Duration) *BeClient {
 	return c
 }
 
+// WithContext 配置请求上下文
+// @Desc 上下文会传递到本次请求发出的每一个HTTP请求（包括下载探测及多线程下载的每个分段），TimeOut仍作为额外的截止时间生效
+// @params ctx context.Context 请求上下文
+// @return     *BeClient       客户端指针
+func (c *BeClient) WithContext(ctx context.Context) *BeClient {
+	if ctx == nil {
+		c.errMsg = errors.New("context is nil")
+		return c
+	}
+	c.ctx = ctx
+	return c
+}
+
 // Body 配置Body请求参数
-// @Desc 会根据Content-Type来格式化数据
+// @Desc 会根据Content-Type来格式化数据；io.Reader、*os.File及func() (io.ReadCloser, error)将作为请求体直接流式发送（multipart/form-data除外），
+// 普通文件、可Seek的读取器及工厂方法可在重试或重定向时重建请求体，读取器由调用方负责关闭
 // @params data interface{} 请求参数
 // @return      *BeClient   客户端指针
 func (c *BeClient) Body(reqBody interface{}) *BeClient {
@@ -154,13 +134,15 @@ func (c *BeClient) Body(reqBody interface{}) *BeClient {
 }
 
 // Download 标记当前请求为下载类型
-// @Desc 使用该接口注册回调可实现下载进度功能
+// @Desc 使用该接口注册回调可实现下载进度功能，回调在下载线程中每次读取后执行（多线程下载时可能并发执行）；
+// 配置了DownloadEvents时改为在回调协程中按事件间隔执行
 // @params savePath string                   下载资源文件保存路径
 // @params callback DownloadCallbackFuncType 下载进度回调函数(请勿在回调函数内处理过多业务，否则可能会造成响应超时)
 // @return          *BeClient                客户端指针
 func (c *BeClient) Download(savePath string, callback DownloadCallbackFuncType) *BeClient {
 	c.isDownloadRequest = true
 	c.downloadSavePath = filepath.Join(strings.TrimSpace(savePath))
+	c.downloadDir = ""
 	c.downloadCallFunc = callback
 	return c
 }
@@ -199,7 +181,7 @@ func (c *BeClient) DownloadMultiThread(maxThreadNum, maxDownloadSize int64) *BeC
 }
 
 // Get GET请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -211,7 +193,7 @@ func (c *BeClient) Get(resData interface{}, resContentType ...ContentTypeType) e
 }
 
 // Head HEAD请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -223,7 +205,7 @@ func (c *BeClient) Head(resData interface{}, resContentType ...ContentTypeType)
 }
 
 // Post POST请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -235,7 +217,7 @@ func (c *BeClient) Post(resData interface{}, resContentType ...ContentTypeType)
 }
 
 // Put PUT请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -247,7 +229,7 @@ func (c *BeClient) Put(resData interface{}, resContentType ...ContentTypeType) e
 }
 
 // Patch PATCH请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -259,7 +241,7 @@ func (c *BeClient) Patch(resData interface{}, resContentType ...ContentTypeType)
 }
 
 // Delete DELETE请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -271,7 +253,7 @@ func (c *BeClient) Delete(resData interface{}, resContentType ...ContentTypeType
 }
 
 // Options OPTIONS请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        指定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
@@ -283,7 +265,7 @@ func (c *BeClient) Options(resData interface{}, resContentType ...ContentTypeTyp
 }
 
 // Trace Trace请求
-// @Desc 第二个参数没有时，第一个参数必须为[]byte类型，将不对响应内容做转换处理
+// @Desc 第二个参数没有时根据响应头的Content-Type自动转换，*[]byte、*string及io.Writer将直接接收原始响应内容
 // @params resData        interface{}        定类型的变量指针
 // @params resContentType ...ContentTypeType 规定的响应内容资源类型，将会根据该类型对响应内容做转换
 // @return                error              错误信息
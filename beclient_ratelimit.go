@@ -0,0 +1,170 @@
+package beclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// NewRateLimiter 创建令牌桶限速器
+// @Desc 令牌桶容量为1秒的流量，可通过RateLimiter或Client.RateLimiter在多个请求及客户端之间共享
+// @params bytesPerSecond int64        每秒允许传输的字节数（小于等于0时不限速）
+// @return                *RateLimiter 限速器指针
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	l := new(RateLimiter)
+	l.SetLimit(bytesPerSecond)
+	return l
+}
+
+// SetLimit 修改限速
+// @Desc 可在传输过程中调整，对共享该限速器的全部请求生效
+// @params bytesPerSecond int64 每秒允许传输的字节数（小于等于0时不限速）
+func (l *RateLimiter) SetLimit(bytesPerSecond int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rate = float64(bytesPerSecond)
+	l.burst = l.rate
+	l.tokens = l.burst
+	l.last = time.Now()
+}
+
+// WaitN 等待n个字节的令牌
+// @Desc 令牌不足时允许预支，预支的部分由后续调用者等待偿还，因此单次可申请超过令牌桶容量的字节数
+// @params ctx context.Context 上下文（取消时返还令牌并返回错误）
+// @params n   int             字节数
+// @return     error           错误信息
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	if l.rate <= 0 || n <= 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+	// 生成令牌
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	// 扣除令牌，不足时计算等待时间
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mutex.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 返还未使用的令牌
+		l.mutex.Lock()
+		l.tokens += float64(n)
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// chunkSize 单次读取的最大字节数
+// @Desc 将单次读取限制在令牌桶容量内，使传输更平滑
+// @params n int 期望读取的字节数
+// @return   int 允许读取的字节数
+func (l *RateLimiter) chunkSize(n int) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.rate <= 0 || float64(n) <= l.burst {
+		return n
+	}
+	if l.burst < 1 {
+		return 1
+	}
+	return int(l.burst)
+}
+
+// wrap 为请求体及响应体添加限速
+// @params next RoundTripFunc 实际发送请求的方法
+// @return      RoundTripFunc 限速后的方法
+func (l *RateLimiter) wrap(next RoundTripFunc) RoundTripFunc {
+	return func(request *http.Request) (*http.Response, error) {
+		ctx := request.Context()
+		// 上传限速（浅拷贝请求，不修改调用方的请求体）
+		if request.Body != nil && request.Body != http.NoBody {
+			request = request.WithContext(ctx)
+			request.Body = &rateLimitedReader{ctx: ctx, limiter: l, reader: request.Body}
+			if getBody := request.GetBody; getBody != nil {
+				request.GetBody = func() (io.ReadCloser, error) {
+					body, err := getBody()
+					if err != nil {
+						return nil, err
+					}
+					return &rateLimitedReader{ctx: ctx, limiter: l, reader: body}, nil
+				}
+			}
+		}
+		res, err := next(request)
+		// 下载限速
+		if res != nil && res.Body != nil {
+			res.Body = &rateLimitedReader{ctx: ctx, limiter: l, reader: res.Body}
+		}
+		return res, err
+	}
+}
+
+// rateLimitedReader 限速读取器
+type rateLimitedReader struct {
+	ctx     context.Context // 请求上下文
+	limiter *RateLimiter    // 限速器
+	reader  io.ReadCloser   // 原始读取器
+}
+
+// Read 读取数据并等待令牌
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p[:r.limiter.chunkSize(len(p))])
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close 关闭原始读取器
+func (r *rateLimitedReader) Close() error {
+	return r.reader.Close()
+}
+
+// RateLimit 限制本次请求的传输速度
+// @Desc 上传的请求体及下载的响应体共用同一限速，多线程下载的全部分段共享该限速
+// @params bytesPerSecond int64     每秒允许传输的字节数（小于等于0时不限速）
+// @return                *BeClient 客户端指针
+func (c *BeClient) RateLimit(bytesPerSecond int64) *BeClient {
+	if bytesPerSecond <= 0 {
+		c.rateLimiter = nil
+		return c
+	}
+	c.rateLimiter = NewRateLimiter(bytesPerSecond)
+	return c
+}
+
+// Limiter 使用共享的限速器
+// @Desc 多个请求使用同一限速器时共享总带宽
+// @params limiter *RateLimiter 限速器（为nil时不限速）
+// @return         *BeClient    客户端指针
+func (c *BeClient) Limiter(limiter *RateLimiter) *BeClient {
+	c.rateLimiter = limiter
+	return c
+}
+
+// Limiter 配置客户端共享的限速器
+// @Desc 通过R()创建的全部请求共享该限速器，同一限速器也可配置到多个客户端实现进程级限速
+// @params limiter *RateLimiter 限速器（为nil时不限速）
+// @return         *Client      可复用客户端指针
+func (c *Client) Limiter(limiter *RateLimiter) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rateLimiter = limiter
+	return c
+}
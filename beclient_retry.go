@@ -0,0 +1,234 @@
+package beclient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultRetryStatusCodes 默认需要重试的响应状态码
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Retry 配置自动重试策略
+// @Desc 默认仅重试幂等请求（GET/HEAD/PUT/DELETE/OPTIONS/TRACE），多线程下载的每个分段会独立重试
+// @params policy RetryPolicy 重试策略
+// @return        *BeClient   客户端指针
+func (c *BeClient) Retry(policy RetryPolicy) *BeClient {
+	// 填充默认值
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = time.Millisecond * 100
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = time.Second * 10
+	}
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = policy.MinBackoff
+	}
+	if len(policy.StatusCodes) == 0 {
+		policy.StatusCodes = defaultRetryStatusCodes
+	}
+	c.retryPolicy = &policy
+	return c
+}
+
+// retryMaxAttempts 获取最大尝试次数
+// @return int 最大尝试次数（包括首次请求）
+func (c *BeClient) retryMaxAttempts() int {
+	if c.retryPolicy == nil || c.retryPolicy.MaxAttempts < 1 {
+		return 1
+	}
+	return c.retryPolicy.MaxAttempts
+}
+
+// retryable 判断请求方法是否允许重试
+// @params request *http.Request 请求体
+// @return         bool          是否允许重试
+func (c *BeClient) retryable(request *http.Request) bool {
+	// 未配置重试策略
+	if c.retryMaxAttempts() <= 1 {
+		return false
+	}
+	// 无法重建的请求体（例如不可Seek的流）只能发送一次
+	if request.Body != nil && request.Body != http.NoBody && request.GetBody == nil {
+		return false
+	}
+	// 判断是否为幂等请求
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return c.retryPolicy.RetryNonIdempotent
+}
+
+// shouldRetry 判断本次请求结果是否需要重试
+// @params request *http.Request  请求体
+// @params res     *http.Response 响应体
+// @params err     error          请求错误
+// @return         bool           是否需要重试
+func (c *BeClient) shouldRetry(request *http.Request, res *http.Response, err error) bool {
+	// 上下文已结束的请求不再重试
+	if request.Context().Err() != nil {
+		return false
+	}
+	// 网络错误需要重试
+	if err != nil || res == nil {
+		return true
+	}
+	// 判断是否为需要重试的状态码
+	return c.retryStatus(res.StatusCode)
+}
+
+// retryStatus 判断响应状态码是否需要重试
+// @params statusCode int  响应状态码
+// @return            bool 是否需要重试
+func (c *BeClient) retryStatus(statusCode int) bool {
+	if c.retryPolicy == nil {
+		return false
+	}
+	for _, code := range c.retryPolicy.StatusCodes {
+		if statusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff 计算第attempt次失败后的等待时间
+// @Desc 优先使用响应头Retry-After，否则使用带抖动的指数退避
+// @params attempt int            已尝试次数
+// @params res     *http.Response 响应体（可为nil）
+// @return         time.Duration  等待时间
+func (c *BeClient) retryBackoff(attempt int, res *http.Response) time.Duration {
+	// 判断服务端是否指定了重试时间
+	if res != nil {
+		if wait, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			return wait
+		}
+	}
+	// 未配置重试策略
+	if c.retryPolicy == nil {
+		return 0
+	}
+	// 指数退避
+	backoff := c.retryPolicy.MinBackoff
+	for i := 1; i < attempt && backoff < c.retryPolicy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.retryPolicy.MaxBackoff {
+		backoff = c.retryPolicy.MaxBackoff
+	}
+	// 抖动，取值范围[backoff/2, backoff]
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryWait 等待重试
+// @params ctx     context.Context 请求上下文
+// @params attempt int             已尝试次数
+// @params res     *http.Response  响应体（可为nil）
+// @return         error           上下文结束时返回上下文错误
+func (c *BeClient) retryWait(ctx context.Context, attempt int, res *http.Response) error {
+	timer := time.NewTimer(c.retryBackoff(attempt, res))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// do 发送请求
+// @Desc 按照重试策略自动重试，每次重试都会重建请求Body
+// @params request *http.Request  请求体
+// @return         *http.Response 响应体
+// @return         error          错误信息
+func (c *BeClient) do(request *http.Request) (*http.Response, error) {
+	// 不可重试时直接发送
+	if !c.retryable(request) {
+		return c.roundTrip(request)
+	}
+	// 按最大尝试次数发送
+	maxAttempts := c.retryMaxAttempts()
+	for attempt := 1; ; attempt++ {
+		// 发送请求
+		res, err := c.roundTrip(request)
+		if attempt >= maxAttempts || !c.shouldRetry(request, res, err) {
+			return res, err
+		}
+		// 等待重试（需要读取Retry-After）
+		waitErr := c.retryWait(request.Context(), attempt, res)
+		// 释放本次响应以便复用连接
+		if res != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1024*64))
+			res.Body.Close()
+		}
+		if waitErr != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, waitErr
+		}
+		// 重建请求
+		retryRequest, rewindErr := rewindRequest(request)
+		if rewindErr != nil {
+			return nil, rewindErr
+		}
+		request = retryRequest
+	}
+}
+
+// rewindRequest 重建请求以便重新发送
+// @params request *http.Request 已发送过的请求
+// @return         *http.Request 新的请求
+// @return         error         错误信息
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	retryRequest := request.Clone(request.Context())
+	// 重建请求Body
+	if request.Body != nil && request.Body != http.NoBody {
+		if request.GetBody == nil {
+			return nil, errNotRewindable
+		}
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retryRequest.Body = body
+	}
+	return retryRequest, nil
+}
+
+// parseRetryAfter 解析Retry-After响应头
+// @params val string        响应头内容（秒数或HTTP日期）
+// @return     time.Duration 等待时间
+// @return     bool          是否解析成功
+func parseRetryAfter(val string) (time.Duration, bool) {
+	if len(val) == 0 {
+		return 0, false
+	}
+	// 秒数
+	if seconds, err := strconv.ParseInt(val, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	// HTTP日期
+	if date, err := http.ParseTime(val); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
@@ -0,0 +1,247 @@
+package beclient
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseDefaultRetry 服务端未指定时的重连间隔
+const sseDefaultRetry = 3 * time.Second
+
+// EventStream 接收服务端推送事件（Server-Sent Events）
+// @Desc 使用已配置的请求类型（默认GET）、请求头、Cookie及地址发起请求，连接断开后按服务端指定的间隔（默认3秒）携带Last-Event-ID自动重连，
+// 直到上下文结束、处理方法返回错误或服务端响应204；非2xx响应返回*StatusError，TimeOut仅限制每次连接等待响应头的时长，接收事件不受限制
+// @params handler SSEHandlerFuncType 事件处理方法
+// @return         error              错误信息（上下文结束时为上下文错误）
+func (c *BeClient) EventStream(handler SSEHandlerFuncType) error {
+	// 是否有全局异常
+	if c.errMsg != nil {
+		return c.errMsg
+	}
+	if handler == nil {
+		return errors.New("event stream handler is nil")
+	}
+	if c.isDownloadRequest {
+		return errors.New("download request is not supported by EventStream")
+	}
+	// 判断是否已经构建
+	if c.client == nil || c.request == nil {
+		if err := c.build(); err != nil {
+			return err
+		}
+	}
+	ctx := c.request.Context()
+	stream := &sseStream{handler: handler, retry: sseDefaultRetry}
+	for {
+		done, err := c.eventStreamOnce(stream)
+		if done {
+			return err
+		}
+		// 等待重连
+		timer := time.NewTimer(stream.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// eventStreamOnce 建立一次事件流连接并接收事件
+// @params stream *sseStream 事件流状态
+// @return        bool       是否停止接收（不再重连）
+// @return        error      错误信息
+func (c *BeClient) eventStreamOnce(stream *sseStream) (bool, error) {
+	request, err := rewindRequest(c.request)
+	if err != nil {
+		return true, err
+	}
+	if len(request.Header.Get("Accept")) == 0 {
+		request.Header.Set("Accept", "text/event-stream")
+	}
+	request.Header.Set("Cache-Control", "no-cache")
+	if len(stream.lastID) > 0 {
+		request.Header.Set("Last-Event-ID", stream.lastID)
+	}
+	// 发起请求
+	res, err := c.doStream(request)
+	if err != nil {
+		// 上下文结束时停止，网络错误时重连
+		if ctxErr := request.Context().Err(); ctxErr != nil {
+			return true, ctxErr
+		}
+		return false, err
+	}
+	defer res.Body.Close()
+	c.response = res
+	// 服务端要求停止重连
+	if res.StatusCode == http.StatusNoContent {
+		return true, nil
+	}
+	if !isSuccessStatus(res.StatusCode) {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024*64))
+		return true, c.statusError(res, body)
+	}
+	if contentType := mediaType(ContentTypeType(res.Header.Get("Content-Type"))); contentType != "text/event-stream" {
+		return true, fmt.Errorf("unexpected event stream content type %q", contentType)
+	}
+	// 接收事件
+	stop, err := stream.read(res.Body)
+	if stop {
+		return true, stopIteration(err)
+	}
+	// 上下文结束时停止，连接断开时重连
+	if ctxErr := request.Context().Err(); ctxErr != nil {
+		return true, ctxErr
+	}
+	return false, err
+}
+
+// sseStream 事件流状态（跨越重连保持）
+type sseStream struct {
+	handler SSEHandlerFuncType // 事件处理方法
+	lastID  string             // 最近一次分发的事件ID
+	retry   time.Duration      // 重连间隔
+}
+
+// read 按规范解析事件流并分发事件
+// @Desc 支持\r\n、\n及\r换行，连接断开时未完成的事件（包括其ID）会被丢弃
+// @params body io.Reader 响应体
+// @return      bool      是否由处理方法停止接收
+// @return      error     读取错误（连接正常结束时为nil）或处理方法返回的错误
+func (s *sseStream) read(body io.Reader) (bool, error) {
+	reader := &sseLineReader{reader: bufio.NewReader(body)}
+	var (
+		event sseEventBuffer
+		first = true
+	)
+	for {
+		line, err := reader.readLine()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		// 忽略开头的BOM
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+		// 空行分发事件
+		if len(line) == 0 {
+			if err = s.dispatch(&event); err != nil {
+				return true, err
+			}
+			continue
+		}
+		// 注释
+		if line[0] == ':' {
+			continue
+		}
+		// 解析字段
+		name, value := line, ""
+		if index := strings.IndexByte(line, ':'); index > -1 {
+			name, value = line[:index], strings.TrimPrefix(line[index+1:], " ")
+		}
+		switch name {
+		case "event":
+			event.event = value
+		case "data":
+			event.data.WriteString(value)
+			event.data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				event.id, event.hasID = value, true
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+				event.retry = s.retry
+			}
+		}
+	}
+}
+
+// dispatch 分发已接收的事件并重置事件缓冲
+// @params event *sseEventBuffer 事件缓冲
+// @return       error           处理方法返回的错误
+func (s *sseStream) dispatch(event *sseEventBuffer) error {
+	defer event.reset()
+	// 事件完整接收后才更新ID（没有data的事件同样更新），避免重连时跳过未分发的事件
+	if event.hasID {
+		s.lastID = event.id
+	}
+	// 没有data的事件不分发
+	if event.data.Len() == 0 {
+		return nil
+	}
+	e := &SSEEvent{
+		ID:    s.lastID,
+		Event: event.event,
+		Data:  strings.TrimSuffix(event.data.String(), "\n"),
+		Retry: event.retry,
+	}
+	if len(e.Event) == 0 {
+		e.Event = "message"
+	}
+	return s.handler(e)
+}
+
+// sseEventBuffer 接收中的事件
+type sseEventBuffer struct {
+	id    string          // 事件ID
+	hasID bool            // 是否收到了id字段
+	event string          // 事件类型
+	data  strings.Builder // 事件数据
+	retry time.Duration   // 重连间隔
+}
+
+// reset 重置事件缓冲
+func (e *sseEventBuffer) reset() {
+	e.id, e.hasID = "", false
+	e.event = ""
+	e.data.Reset()
+	e.retry = 0
+}
+
+// sseLineReader 事件流按行读取器
+// @Desc \r后立即返回行，不等待可能跟随的\n，避免阻塞实时事件
+type sseLineReader struct {
+	reader *bufio.Reader // 缓冲读取器
+	skipLF bool          // 上一行以\r结尾，需要跳过紧随的\n
+}
+
+// readLine 读取一行（不含换行符）
+// @return string 行内容
+// @return error  读取错误（未以换行结尾的最后一行会被丢弃）
+func (r *sseLineReader) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if r.skipLF {
+			r.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+		switch b {
+		case '\n':
+			return string(line), nil
+		case '\r':
+			r.skipLF = true
+			return string(line), nil
+		}
+		line = append(line, b)
+	}
+}
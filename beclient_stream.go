@@ -0,0 +1,273 @@
+package beclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// errorType error接口类型
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Method 配置请求类型
+// @Desc 用于Do、Stream等不指定请求类型的接口（默认GET）
+// @params method MethodType 请求类型
+// @return        *BeClient  客户端指针
+func (c *BeClient) Method(method MethodType) *BeClient {
+	c.method = method
+	return c
+}
+
+// Do 发送请求并返回未读取的响应
+// @Desc 响应体不会被读取，调用方需要自行读取并关闭；开启StatusCheck时非2xx响应会被读取并返回*StatusError；
+// TimeOut仅限制收到响应头之前的时长，读取响应体不受限制（可通过Context控制）
+// @return *http.Response 响应体
+// @return error          错误信息
+func (c *BeClient) Do() (*http.Response, error) {
+	// 是否有全局异常
+	if c.errMsg != nil {
+		return nil, c.errMsg
+	}
+	if c.isDownloadRequest {
+		return nil, errors.New("download request is not supported by Do")
+	}
+	// 判断是否已经构建
+	if c.client == nil || c.request == nil {
+		if err := c.build(); err != nil {
+			return nil, err
+		}
+	}
+	// 发起请求
+	res, err := c.doStream(c.request)
+	if err != nil {
+		return nil, err
+	}
+	c.response = res
+	// 判断是否需要将非2xx响应视为错误
+	if c.statusCheck && !isSuccessStatus(res.StatusCode) {
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.statusError(res, body)
+	}
+	return res, nil
+}
+
+// doStream 发送读取时长不受限制的流式请求
+// @Desc HTTP客户端不设置总超时，TimeOut仅限制发送请求（含重试）到收到响应头的时长，之后响应体的读取只受上下文控制
+// @params request *http.Request  请求体
+// @return         *http.Response 响应体
+// @return         error          错误信息
+func (c *BeClient) doStream(request *http.Request) (*http.Response, error) {
+	// Client.Timeout包含读取响应体的时长，流式请求不能使用
+	if c.client.Timeout != 0 {
+		client := *c.client
+		client.Timeout = 0
+		c.client = &client
+	}
+	if c.timeOut <= 0 {
+		res, err := c.do(request)
+		if err == nil && res == nil {
+			return nil, errors.New("http.Response is nil pointer")
+		}
+		return res, err
+	}
+	// 收到响应头后停止计时，响应体关闭时释放上下文
+	ctx, cancel := context.WithCancel(request.Context())
+	timer := time.AfterFunc(c.timeOut, cancel)
+	res, err := c.do(request.WithContext(ctx))
+	if !timer.Stop() && request.Context().Err() == nil {
+		if res != nil {
+			res.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("timeout awaiting response headers after %s: %w", c.timeOut, context.DeadlineExceeded)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if res == nil {
+		cancel()
+		return nil, errors.New("http.Response is nil pointer")
+	}
+	res.Body = &cancelReadCloser{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelReadCloser 关闭时取消上下文的响应体
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc // 取消上下文的方法
+}
+
+// Close 关闭响应体并取消上下文
+// @return error 错误信息
+func (r *cancelReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// Stream 发送请求并流式读取响应
+// @Desc 响应体不会被缓存到内存，回调返回后自动关闭响应体，超时说明请参考Do
+// @params fn func(r io.Reader) error 读取响应体的回调
+// @return    error                   错误信息
+func (c *BeClient) Stream(fn func(r io.Reader) error) error {
+	res, err := c.Do()
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return fn(res.Body)
+}
+
+// JSONArray 发送请求并逐个解码响应中JSON数组的元素
+// @Desc 回调参数请参考DecodeJSONArray
+// @params fn interface{} 元素回调，类型为func(T) error或func(*T) error
+// @return    error       错误信息
+func (c *BeClient) JSONArray(fn interface{}) error {
+	return c.Stream(func(r io.Reader) error {
+		return DecodeJSONArray(r, fn)
+	})
+}
+
+// NDJSON 发送请求并逐行解码响应中的NDJSON（每行一个JSON）
+// @Desc 回调参数请参考DecodeNDJSON
+// @params fn interface{} 元素回调，类型为func(T) error或func(*T) error
+// @return    error       错误信息
+func (c *BeClient) NDJSON(fn interface{}) error {
+	return c.Stream(func(r io.Reader) error {
+		return DecodeNDJSON(r, fn)
+	})
+}
+
+// DecodeJSONArray 逐个解码JSON数组的元素
+// @Desc 每次只解码一个元素，适用于超大的JSON数组；null视为空数组，回调返回ErrStopIteration时停止解码且不返回错误
+// @params r  io.Reader   JSON数组内容
+// @params fn interface{} 元素回调，类型为func(T) error或func(*T) error
+// @return    error       错误信息
+func DecodeJSONArray(r io.Reader, fn interface{}) error {
+	callback, err := newElementCallback(fn)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(r)
+	// 数组开始
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected json array, got %v", token)
+	}
+	// 逐个解码元素
+	for decoder.More() {
+		if err = callback.call(decoder.Decode); err != nil {
+			return stopIteration(err)
+		}
+	}
+	// 数组结束
+	_, err = decoder.Token()
+	return err
+}
+
+// DecodeNDJSON 逐行解码NDJSON（每行一个JSON）
+// @Desc 忽略空行，单行长度不受限制，回调返回ErrStopIteration时停止解码且不返回错误
+// @params r  io.Reader   NDJSON内容
+// @params fn interface{} 元素回调，类型为func(T) error或func(*T) error
+// @return    error       错误信息
+func DecodeNDJSON(r io.Reader, fn interface{}) error {
+	callback, err := newElementCallback(fn)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		content, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if content = bytes.TrimSpace(content); len(content) > 0 {
+			err = callback.call(func(v interface{}) error {
+				if err := json.Unmarshal(content, v); err != nil {
+					return fmt.Errorf("ndjson line %d: %w", line, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return stopIteration(err)
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// elementCallback 逐个解码的元素回调
+type elementCallback struct {
+	fn      reflect.Value // 回调方法
+	elem    reflect.Type  // 元素类型
+	pointer bool          // 回调参数是否为指针
+}
+
+// newElementCallback 校验并创建元素回调
+// @params fn interface{}      元素回调，类型为func(T) error或func(*T) error
+// @return    *elementCallback 元素回调
+// @return    error            错误信息
+func newElementCallback(fn interface{}) (*elementCallback, error) {
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func || val.IsNil() ||
+		val.Type().NumIn() != 1 || val.Type().NumOut() != 1 || val.Type().Out(0) != errorType {
+		return nil, fmt.Errorf("callback must be func(T) error or func(*T) error, got %T", fn)
+	}
+	typ := val.Type()
+	callback := &elementCallback{fn: val, elem: typ.In(0)}
+	if callback.elem.Kind() == reflect.Ptr {
+		callback.elem = callback.elem.Elem()
+		callback.pointer = true
+	}
+	return callback, nil
+}
+
+// call 解码一个元素并回调
+// @params decode func(v interface{}) error 将元素解码到v的方法
+// @return        error                     解码或回调的错误信息
+func (e *elementCallback) call(decode func(v interface{}) error) error {
+	ptr := reflect.New(e.elem)
+	if err := decode(ptr.Interface()); err != nil {
+		return err
+	}
+	arg := ptr
+	if !e.pointer {
+		arg = ptr.Elem()
+	}
+	if err, _ := e.fn.Call([]reflect.Value{arg})[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stopIteration 将ErrStopIteration转换为空错误
+// @params err error 回调的错误信息
+// @return     error 错误信息
+func stopIteration(err error) error {
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
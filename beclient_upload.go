@@ -0,0 +1,171 @@
+package beclient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// UploadProgress 配置上传进度回调
+// @Desc 请求体每次被读取时回调，重试或重定向重新发送请求体时从0开始重新计算
+// @params callback UploadCallbackFuncType 上传进度回调函数(请勿在回调函数内处理过多业务，否则会阻塞上传)
+// @return          *BeClient              客户端指针
+func (c *BeClient) UploadProgress(callback UploadCallbackFuncType) *BeClient {
+	c.uploadCallFunc = callback
+	return c
+}
+
+// requestBody 获取请求体
+// @Desc 流式请求参数直接作为请求体，其他请求参数根据资源类型转换
+// @return body    io.Reader                      请求体
+// @return getBody func() (io.ReadCloser, error) 重建请求体的方法（为nil时由http.NewRequest决定）
+// @return size    int64                          请求体大小（未知时为-1）
+// @return err     error                          错误信息
+func (c *BeClient) requestBody() (body io.Reader, getBody func() (io.ReadCloser, error), size int64, err error) {
+	// multipart/form-data的请求参数为表单字段
+	if c.contentType != ContentTypeFormBody {
+		switch val := c.data.(type) {
+		case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+			// http.NewRequest可以获取大小并重建
+			return val.(io.Reader), nil, -1, nil
+		case func() (io.ReadCloser, error):
+			if body, err = val(); err != nil {
+				return nil, nil, -1, err
+			}
+			return body, val, readerSize(body), nil
+		case io.Reader:
+			body, getBody, size, err = streamBody(val)
+			return body, getBody, size, err
+		}
+	}
+	// 转换请求参数
+	reqBody, err := c.requestConvertData()
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	return bytes.NewReader(reqBody), nil, int64(len(reqBody)), nil
+}
+
+// streamBody 将io.Reader转换为请求体
+// @Desc 普通文件按当前位置之后的内容发送，可Seek的读取器重建时回到当前位置，其他读取器无法重建（不会重试）；
+// 调用方负责关闭读取器
+// @params reader io.Reader                      读取器
+// @return        io.Reader                      请求体
+// @return        func() (io.ReadCloser, error) 重建请求体的方法（无法重建时为nil）
+// @return        int64                          请求体大小（未知时为-1）
+// @return        error                          错误信息
+func streamBody(reader io.Reader) (io.Reader, func() (io.ReadCloser, error), int64, error) {
+	// 普通文件使用独立的读取位置，可以重复读取
+	if file, ok := reader.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			offset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, nil, -1, err
+			}
+			size := info.Size() - offset
+			getBody := func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(io.NewSectionReader(file, offset, size)), nil
+			}
+			body, _ := getBody()
+			return body, getBody, size, nil
+		}
+	}
+	// 可Seek的读取器重建时回到当前位置
+	if seeker, ok := reader.(io.Seeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			size := readerSize(reader)
+			getBody := func() (io.ReadCloser, error) {
+				if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+					return nil, err
+				}
+				return ioutil.NopCloser(reader), nil
+			}
+			return ioutil.NopCloser(reader), getBody, size, nil
+		}
+	}
+	return ioutil.NopCloser(reader), nil, readerSize(reader), nil
+}
+
+// readerSize 获取读取器剩余内容的大小
+// @params reader io.Reader 读取器
+// @return        int64     剩余内容大小（未知时为-1）
+func readerSize(reader io.Reader) int64 {
+	switch val := reader.(type) {
+	case interface{ Len() int }:
+		return int64(val.Len())
+	case *os.File:
+		info, err := val.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := val.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	case io.Seeker:
+		offset, err := val.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := val.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err = val.Seek(offset, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - offset
+	}
+	return -1
+}
+
+// uploadProgressReader 上传进度读取器
+type uploadProgressReader struct {
+	reader    io.ReadCloser          // 原始请求体
+	callback  UploadCallbackFuncType // 上传进度回调函数
+	currSize  int64                  // 已上传大小
+	totalSize int64                  // 请求体总大小（未知时为-1）
+}
+
+// Read 读取请求体并回调上传进度
+func (r *uploadProgressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.currSize += int64(n)
+		r.callback(float64(r.currSize), float64(r.totalSize))
+	}
+	return n, err
+}
+
+// Close 关闭原始请求体
+func (r *uploadProgressReader) Close() error {
+	return r.reader.Close()
+}
+
+// trackUpload 为请求体添加上传进度回调
+func (c *BeClient) trackUpload() {
+	request := c.request
+	if c.uploadCallFunc == nil || request.Body == nil || request.Body == http.NoBody {
+		return
+	}
+	// 请求体不为空时ContentLength为0表示大小未知
+	size := request.ContentLength
+	if size == 0 {
+		size = -1
+	}
+	request.Body = &uploadProgressReader{reader: request.Body, callback: c.uploadCallFunc, totalSize: size}
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &uploadProgressReader{reader: body, callback: c.uploadCallFunc, totalSize: size}, nil
+		}
+	}
+}
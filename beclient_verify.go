@@ -0,0 +1,248 @@
+package beclient
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// downloadChecksum 下载内容的期望校验值
+type downloadChecksum struct {
+	algo     HashAlgo // 哈希算法
+	expected []byte   // 期望的哈希值
+	source   string   // 校验值来源（DownloadVerify或响应头名称）
+}
+
+// downloadHasher 下载内容哈希计算器
+type downloadHasher struct {
+	checksums []*downloadChecksum    // 期望校验值
+	hashes    map[HashAlgo]hash.Hash // 各算法的哈希计算器
+	writer    io.Writer              // 同时写入全部哈希计算器
+}
+
+// Error 实现error接口
+// @return string 错误信息
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("download checksum mismatch (%s from %s): expected %s, actual %s", e.Algo, e.Source, e.Expected, e.Actual)
+}
+
+// DownloadVerify 配置下载内容的校验值
+// @Desc 下载完成后校验文件内容，不一致时返回*ChecksumError并删除已下载的临时文件（不会替换到保存路径）；
+// 无论是否配置，响应头中的Content-MD5、Digest及Repr-Digest都会被自动校验（Transport自动解压gzip响应时除外，此时响应头的校验值对应压缩后的内容）
+// @params algo        HashAlgo  哈希算法（md5、sha1、sha256、sha512）
+// @params expectedHex string    期望的十六进制哈希值
+// @return             *BeClient 客户端指针
+func (c *BeClient) DownloadVerify(algo HashAlgo, expectedHex string) *BeClient {
+	// 判断算法是否支持
+	if newHash(algo) == nil {
+		c.errMsg = fmt.Errorf("unsupported hash algorithm: %s", algo)
+		return c
+	}
+	// 解析期望值
+	expected, err := hex.DecodeString(strings.TrimSpace(expectedHex))
+	if err != nil {
+		c.errMsg = err
+		return c
+	}
+	c.downloadChecksums = append(c.downloadChecksums, &downloadChecksum{
+		algo:     algo,
+		expected: expected,
+		source:   "DownloadVerify",
+	})
+	return c
+}
+
+// newDownloadHasher 根据响应头及配置的校验值创建哈希计算器
+// @params header  http.Header     完整资源的响应头
+// @params partial bool            响应是否为部分内容（部分内容的Content-MD5不代表完整资源）
+// @return         *downloadHasher 哈希计算器（无需校验时为nil）
+func (c *BeClient) newDownloadHasher(header http.Header, partial bool) *downloadHasher {
+	// 收集全部期望校验值
+	checksums := append([]*downloadChecksum(nil), c.downloadChecksums...)
+	checksums = append(checksums, headerChecksums(header, partial)...)
+	if len(checksums) == 0 {
+		return nil
+	}
+	// 每个算法只计算一次
+	h := &downloadHasher{
+		checksums: checksums,
+		hashes:    make(map[HashAlgo]hash.Hash),
+	}
+	writers := make([]io.Writer, 0, len(checksums))
+	for _, checksum := range checksums {
+		if _, ok := h.hashes[checksum.algo]; !ok {
+			h.hashes[checksum.algo] = newHash(checksum.algo)
+			writers = append(writers, h.hashes[checksum.algo])
+		}
+	}
+	h.writer = io.MultiWriter(writers...)
+	return h
+}
+
+// Write 写入需要计算哈希的内容
+// @params p []byte 内容
+// @return n int    写入长度
+// @return   error  错误信息
+func (h *downloadHasher) Write(p []byte) (n int, err error) {
+	return h.writer.Write(p)
+}
+
+// hashFile 计算文件指定长度的内容
+// @params path string 文件路径
+// @params size int64  需要计算的长度（小于0时计算整个文件）
+// @return      error  错误信息
+func (h *downloadHasher) hashFile(path string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if size < 0 {
+		_, err = io.Copy(h, file)
+	} else {
+		_, err = io.CopyN(h, file, size)
+	}
+	return err
+}
+
+// verify 校验计算结果
+// @return error 不一致时返回*ChecksumError
+func (h *downloadHasher) verify() error {
+	for _, checksum := range h.checksums {
+		actual := h.hashes[checksum.algo].Sum(nil)
+		if hex.EncodeToString(actual) != hex.EncodeToString(checksum.expected) {
+			return &ChecksumError{
+				Algo:     checksum.algo,
+				Source:   checksum.source,
+				Expected: hex.EncodeToString(checksum.expected),
+				Actual:   hex.EncodeToString(actual),
+			}
+		}
+	}
+	return nil
+}
+
+// verifyDownload 校验已下载的文件
+// @Desc 下载到文件时，校验失败会删除已下载的临时文件及断点续传状态
+// @params h *downloadHasher 哈希计算器（为nil时不校验）
+// @return   error           错误信息
+func (c *BeClient) verifyDownload(h *downloadHasher) error {
+	if h == nil {
+		return nil
+	}
+	err := h.verify()
+	if err != nil && c.downloadWriter == nil && c.downloadWriterAt == nil {
+		_ = os.Remove(c.downloadTempPath())
+		_ = removeDownloadState(c.downloadStatePath())
+	}
+	return err
+}
+
+// checksumHeader 获取可用于校验下载内容的响应头
+// @Desc Transport自动解压的响应，响应头中的校验值对应压缩后的内容，不能用于校验解压后的内容
+// @params res *http.Response 响应体
+// @return     http.Header    响应头（自动解压时为nil）
+func checksumHeader(res *http.Response) http.Header {
+	if res.Uncompressed {
+		return nil
+	}
+	return res.Header
+}
+
+// headerChecksums 解析响应头中的校验值
+// @Desc 支持Content-MD5、Digest（RFC 3230）及Repr-Digest（RFC 9530）
+// @params header  http.Header         响应头
+// @params partial bool                响应是否为部分内容
+// @return         []*downloadChecksum 校验值
+func headerChecksums(header http.Header, partial bool) []*downloadChecksum {
+	var checksums []*downloadChecksum
+	// Content-MD5仅代表本次响应的内容
+	if val := header.Get("Content-MD5"); len(val) > 0 && !partial {
+		if expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(val)); err == nil {
+			checksums = append(checksums, &downloadChecksum{algo: HashMD5, expected: expected, source: "Content-MD5"})
+		}
+	}
+	// Digest: SHA-256=base64, MD5=base64
+	for _, item := range splitHeaderList(header.Values("Digest")) {
+		index := strings.Index(item, "=")
+		if index < 0 {
+			continue
+		}
+		algo := digestAlgo(item[:index])
+		expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(item[index+1:]))
+		if len(algo) > 0 && err == nil {
+			checksums = append(checksums, &downloadChecksum{algo: algo, expected: expected, source: "Digest"})
+		}
+	}
+	// Repr-Digest: sha-256=:base64:
+	for _, item := range splitHeaderList(header.Values("Repr-Digest")) {
+		index := strings.Index(item, "=")
+		if index < 0 {
+			continue
+		}
+		algo := digestAlgo(item[:index])
+		expected, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimSpace(item[index+1:]), ":"))
+		if len(algo) > 0 && err == nil {
+			checksums = append(checksums, &downloadChecksum{algo: algo, expected: expected, source: "Repr-Digest"})
+		}
+	}
+	return checksums
+}
+
+// splitHeaderList 拆分逗号分隔的响应头列表
+// @params vals []string 响应头内容
+// @return      []string 拆分后的内容
+func splitHeaderList(vals []string) []string {
+	var items []string
+	for _, val := range vals {
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// digestAlgo 转换Digest中的算法名称
+// @params name string   算法名称
+// @return      HashAlgo 哈希算法（不支持时为空）
+func digestAlgo(name string) HashAlgo {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "md5":
+		return HashMD5
+	case "sha", "sha-1":
+		return HashSHA1
+	case "sha-256":
+		return HashSHA256
+	case "sha-512":
+		return HashSHA512
+	}
+	return ""
+}
+
+// newHash 创建哈希计算器
+// @params algo HashAlgo  哈希算法
+// @return      hash.Hash 哈希计算器（不支持时为nil）
+func newHash(algo HashAlgo) hash.Hash {
+	switch algo {
+	case HashMD5:
+		return md5.New()
+	case HashSHA1:
+		return sha1.New()
+	case HashSHA256:
+		return sha256.New()
+	case HashSHA512:
+		return sha512.New()
+	}
+	return nil
+}
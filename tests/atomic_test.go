@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestAtomicDownload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 传输一半后断开连接
+		w.Header().Set("Content-Length", "1024")
+		w.Write(make([]byte, 512))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	savePath := filepath.Join(dir, "data.bin")
+	if err := ioutil.WriteFile(savePath, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := beclient.New(srv.URL).Download(savePath, nil).Get(nil); err == nil {
+		t.Fatal("expected download to fail")
+	}
+	// 原有文件保持不变
+	if content, _ := ioutil.ReadFile(savePath); string(content) != "old" {
+		t.Fatalf("existing file was modified: %q", content)
+	}
+	// 默认不保留未完成的临时文件
+	if _, err := os.Stat(savePath + ".part"); !os.IsNotExist(err) {
+		t.Fatal("partial file should be removed")
+	}
+
+	// 保留未完成的临时文件
+	tempDir := filepath.Join(dir, "tmp")
+	err := beclient.New(srv.URL).
+		DownloadTempDir(tempDir).
+		DownloadKeepPartial(true).
+		Download(savePath, nil).
+		Get(nil)
+	if err == nil {
+		t.Fatal("expected download to fail")
+	}
+	matches, _ := filepath.Glob(filepath.Join(tempDir, "data.bin.*.part"))
+	if len(matches) != 1 {
+		t.Fatalf("expected partial file in temp dir, got %v", matches)
+	}
+}
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestClientReuse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s|%s", r.URL.Path, r.Header.Get("X-Token"), r.URL.Query().Get("v"), r.URL.Query().Get("i"))
+	}))
+	defer srv.Close()
+
+	client := beclient.NewClient(srv.URL+"/api?v=1").Header("X-Token", "abc")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var res []byte
+			err := client.R().
+				Path(fmt.Sprintf("/item/%d", i)).
+				Query("i", fmt.Sprint(i)).
+				Get(&res)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			want := fmt.Sprintf("/api/item/%d|abc|1|%d", i, i)
+			if string(res) != want {
+				t.Errorf("got %q, want %q", res, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+// upperCodec 将字符串转换为大写的编解码器
+type upperCodec struct{}
+
+func (upperCodec) ContentTypes() []beclient.ContentTypeType {
+	return []beclient.ContentTypeType{"text/x-upper"}
+}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("upperCodec: value is not string")
+	}
+	return []byte(strings.ToUpper(s)), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	s, ok := v.(*string)
+	if !ok {
+		return errors.New("upperCodec: dst is not *string")
+	}
+	*s = "decoded:" + string(data)
+	return nil
+}
+
+func TestClientCodec(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	client := beclient.NewClient(srv.URL).Codec(upperCodec{})
+	var res string
+	err := client.R().
+		ContentType("text/x-upper").
+		Body("hello").
+		Post(&res, "text/x-upper; charset=utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "decoded:HELLO" {
+		t.Fatalf("unexpected response %q", res)
+	}
+
+	// 未注册的客户端不支持该资源类型
+	err = beclient.New(srv.URL).ContentType("text/x-upper").Body("hello").Post(nil)
+	if err == nil {
+		t.Fatal("expected unsupported resource type error")
+	}
+}
+
+func TestBuiltinCodecs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	type payload struct {
+		Name string `json:"name" xml:"name"`
+	}
+	for _, contentType := range []beclient.ContentTypeType{beclient.ContentTypeJson, beclient.ContentTypeAppXml} {
+		var res payload
+		err := beclient.New(srv.URL).ContentType(contentType).Body(payload{Name: "beclient"}).Post(&res, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Name != "beclient" {
+			t.Fatalf("%s: unexpected response %+v", contentType, res)
+		}
+	}
+}
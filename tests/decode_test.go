@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+// decodeServer 按请求路径返回不同资源类型的响应
+func decodeServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name":"beclient"}`))
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Write([]byte(`{"name":"problem"}`))
+		case "/xml":
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+			w.Write([]byte(`<item><name>atom</name></item>`))
+		default:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("raw"))
+		}
+	}))
+}
+
+type decodeItem struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestAutoDecode(t *testing.T) {
+	srv := decodeServer()
+	defer srv.Close()
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/json", "beclient"},
+		{"/problem", "problem"},
+		{"/xml", "atom"},
+	}
+	for _, val := range cases {
+		var res decodeItem
+		if err := beclient.New(srv.URL).Path(val.path).Get(&res); err != nil {
+			t.Fatalf("%s: %v", val.path, err)
+		}
+		if res.Name != val.want {
+			t.Fatalf("%s: unexpected name %q", val.path, res.Name)
+		}
+		// 显式指定自动模式
+		res = decodeItem{}
+		if err := beclient.New(srv.URL).Path(val.path).Get(&res, beclient.ContentTypeAuto); err != nil {
+			t.Fatalf("%s: %v", val.path, err)
+		}
+		if res.Name != val.want {
+			t.Fatalf("%s: unexpected name %q", val.path, res.Name)
+		}
+	}
+
+	// 无法识别的资源类型
+	var res decodeItem
+	if err := beclient.New(srv.URL).Path("/raw").Get(&res); err == nil {
+		t.Fatal("expected unsupported content type error")
+	}
+}
+
+func TestRawDecode(t *testing.T) {
+	srv := decodeServer()
+	defer srv.Close()
+
+	var str string
+	if err := beclient.New(srv.URL).Path("/json").Get(&str); err != nil {
+		t.Fatal(err)
+	}
+	if str != `{"name":"beclient"}` {
+		t.Fatalf("unexpected string %q", str)
+	}
+
+	var buf bytes.Buffer
+	if err := beclient.New(srv.URL).Path("/raw").Get(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "raw" {
+		t.Fatalf("unexpected buffer %q", buf.String())
+	}
+
+	var raw []byte
+	if err := beclient.New(srv.URL).Path("/xml").Get(&raw, beclient.ContentTypeAuto); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != `<item><name>atom</name></item>` {
+		t.Fatalf("unexpected bytes %q", raw)
+	}
+}
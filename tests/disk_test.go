@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadPreallocate(t *testing.T) {
+	data := randomData(512 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		DownloadPreallocate().
+		DownloadSync(beclient.SyncPeriodic, time.Millisecond*10).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+}
+
+func TestDownloadInsufficientSpace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("free space check is only supported on linux")
+	}
+	var gets int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// 声明一个远超磁盘容量的资源
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", "1152921504606846976")
+			return
+		}
+		gets++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := beclient.New(srv.URL).
+		DownloadPreallocate().
+		Download(filepath.Join(t.TempDir(), "data.bin"), nil).
+		Get(nil)
+	if !errors.Is(err, beclient.ErrInsufficientSpace) {
+		t.Fatalf("expected ErrInsufficientSpace, got %v", err)
+	}
+	if gets != 0 {
+		t.Fatalf("expected no download requests, got %d", gets)
+	}
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code":1001,"message":"boom"}`))
+	}))
+	defer srv.Close()
+
+	type apiError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	var res struct {
+		Data string `json:"data"`
+	}
+	var apiErr apiError
+	err := beclient.New(srv.URL).
+		ErrorBody(&apiErr, beclient.ContentTypeJson).
+		Get(&res, beclient.ContentTypeJson)
+	var statusErr *beclient.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected *StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusInternalServerError || statusErr.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected status error: %+v", statusErr)
+	}
+	if apiErr.Code != 1001 || apiErr.Message != "boom" {
+		t.Fatalf("error body not decoded: %+v", apiErr)
+	}
+
+	// 未开启StatusCheck时保持原有行为
+	var raw []byte
+	if err = beclient.New(srv.URL).Get(&raw); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrorBodyProbeFallback(t *testing.T) {
+	data := randomData(1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// HEAD及Range探测失败，普通GET成功
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if len(r.Header.Get("Range")) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			w.Write([]byte(`{"message":"probe"}`))
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(srv.URL).
+		ErrorBody(&apiErr, beclient.ContentTypeJson).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 回退下载成功时探测的错误响应不会被转换
+	if len(apiErr.Message) > 0 {
+		t.Fatalf("probe error decoded into ErrorBody: %+v", apiErr)
+	}
+
+	// Stat返回探测错误时才转换
+	_, err = beclient.New(srv.URL).ErrorBody(&apiErr, beclient.ContentTypeJson).Stat()
+	var statusErr *beclient.StatusError
+	if !errors.As(err, &statusErr) || apiErr.Message != "probe" {
+		t.Fatalf("unexpected stat error %v with error body %+v", err, apiErr)
+	}
+}
+
+func TestErrorBodyRangeSegments(t *testing.T) {
+	data := randomData(512 * 1024)
+	var failed sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeVal := r.Header.Get("Range")
+		switch {
+		// 探测成功
+		case r.Method == http.MethodHead || rangeVal == "bytes=0-0":
+		// 每个分段首次请求返回503
+		case r.URL.Path == "/retry":
+			if _, loaded := failed.LoadOrStore(rangeVal, true); !loaded {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"message":"busy"}`))
+				return
+			}
+		// 分段总是返回500
+		case r.URL.Path == "/fail":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"boom"}`))
+			return
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	// 重试成功的分段错误不会被转换
+	err := beclient.New(srv.URL).
+		Path("/retry").
+		Retry(beclient.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}).
+		ErrorBody(&apiErr, beclient.ContentTypeJson).
+		DownloadMultiThread(8, 1024*64).
+		DownloadBufferSize(1024).
+		Download(filepath.Join(t.TempDir(), "retry.bin"), nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apiErr.Message) > 0 {
+		t.Fatalf("retried segment error decoded into ErrorBody: %+v", apiErr)
+	}
+
+	// 多个分段同时失败时只转换返回的错误
+	err = beclient.New(srv.URL).
+		Path("/fail").
+		ErrorBody(&apiErr, beclient.ContentTypeJson).
+		DownloadMultiThread(8, 1024*64).
+		DownloadBufferSize(1024).
+		Download(filepath.Join(t.TempDir(), "fail.bin"), nil).
+		Get(nil)
+	var statusErr *beclient.StatusError
+	if !errors.As(err, &statusErr) || apiErr.Message != "boom" {
+		t.Fatalf("unexpected error %v with error body %+v", err, apiErr)
+	}
+}
@@ -0,0 +1,21 @@
+package tests
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// randomData 生成指定大小的随机内容
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+// serveData 以支持Range的方式响应内容
+func serveData(w http.ResponseWriter, r *http.Request, data []byte) {
+	w.Header().Set("ETag", `"beclient-test"`)
+	http.ServeContent(w, r, "data.bin", time.Unix(1600000000, 0), bytes.NewReader(data))
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+// encryptAES128 使用AES-128（CBC、PKCS7填充）加密
+func encryptAES128(plain, key, iv []byte) []byte {
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	plain = append(append([]byte(nil), plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, plain)
+	return out
+}
+
+func TestDownloadHLS(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	segments := [][]byte{randomData(1000), randomData(2000), randomData(3000)}
+	// 第一个分段使用分段序号作为初始向量
+	seqIV := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(seqIV[8:], 7)
+	explicitIV := []byte("fedcba9876543210")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n",
+			"#EXT-X-STREAM-INF:BANDWIDTH=200000,RESOLUTION=640x360,CODECS=\"avc1.4d401f,mp4a.40.2\"\n",
+			"low/index.m3u8\n",
+			"#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=1280x720\n",
+			"high/index.m3u8\n")
+	})
+	mux.HandleFunc("/low/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("low bandwidth variant should not be selected")
+	})
+	mux.HandleFunc("/high/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:7\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"/key\"\n#EXTINF:4.0,\nseg0.ts\n"+
+			"#EXT-X-KEY:METHOD=AES-128,URI=\"/key\",IV=0x%x\n#EXTINF:4.0,\nseg1.ts\n"+
+			"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4.0,\nseg2.ts\n#EXT-X-ENDLIST\n", explicitIV)
+	})
+	mux.HandleFunc("/key", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(key)
+	})
+	mux.HandleFunc("/high/seg0.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encryptAES128(segments[0], key, seqIV))
+	})
+	mux.HandleFunc("/high/seg1.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(encryptAES128(segments[1], key, explicitIV))
+	})
+	mux.HandleFunc("/high/seg2.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(segments[2])
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "video.ts")
+	var lastCurr, lastTotal float64
+	err := beclient.New(srv.URL).
+		Path("/master.m3u8").
+		DownloadMultiThread(2, 1024).
+		DownloadHLS(savePath, func(currSize, totalSize float64) {
+			lastCurr, lastTotal = currSize, totalSize
+		}).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, bytes.Join(segments, nil)) {
+		t.Fatal("hls content mismatch")
+	}
+	// 进度为已下载的字节数，完成时总大小为实际大小
+	size := float64(len(got))
+	if lastCurr != size || lastTotal != size {
+		t.Fatalf("unexpected progress %v/%v", lastCurr, lastTotal)
+	}
+}
+
+func TestDownloadHLSByteRange(t *testing.T) {
+	media := randomData(4000)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-MAP:URI=\"media.mp4\",BYTERANGE=\"100@0\"\n",
+			"#EXTINF:4.0,\n#EXT-X-BYTERANGE:1000@100\nmedia.mp4\n",
+			"#EXTINF:4.0,\n#EXT-X-BYTERANGE:2000\nmedia.mp4\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("/media.mp4", func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, media)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "video.mp4")
+	err := beclient.New(srv.URL).
+		Path("/index.m3u8").
+		DownloadHLS(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, media[:3100]) {
+		t.Fatalf("hls byte range content mismatch: got %d bytes", len(got))
+	}
+}
+
+func TestDownloadHLSFailureWaits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXTINF:4.0,\nseg0.ts\n#EXTINF:4.0,\nseg1.ts\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("/seg0.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/seg1.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(randomData(1000))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// 第二个分段在第一个分段失败后才发出请求
+	var returned, lateRequests int32
+	slow := func(next beclient.RoundTripFunc) beclient.RoundTripFunc {
+		return func(request *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(request.URL.Path, "/seg1.ts") {
+				time.Sleep(200 * time.Millisecond)
+				if atomic.LoadInt32(&returned) == 1 {
+					atomic.AddInt32(&lateRequests, 1)
+				}
+			}
+			return next(request)
+		}
+	}
+	err := beclient.New(srv.URL).
+		Path("/index.m3u8").
+		Use(slow).
+		DownloadMultiThread(2, 1024).
+		DownloadHLS(filepath.Join(t.TempDir(), "video.ts"), nil).
+		Get(nil)
+	atomic.StoreInt32(&returned, 1)
+	var statusErr *beclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 status error, got %v", err)
+	}
+	// 返回前已等待全部分段协程结束
+	time.Sleep(300 * time.Millisecond)
+	if n := atomic.LoadInt32(&lateRequests); n != 0 {
+		t.Fatalf("%d segment requests sent after Get returned", n)
+	}
+}
+
+func TestDownloadHLSWriter(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, "#EXTM3U\n#EXTINF:4.0,\nseg0.ts\n#EXT-X-ENDLIST\n")
+	}))
+	defer srv.Close()
+
+	// HLS不能写入自定义写入目标，播放列表内容不会被写入
+	var buf bytes.Buffer
+	err := beclient.New(srv.URL).
+		DownloadHLS(filepath.Join(t.TempDir(), "video.ts"), nil).
+		DownloadTo(&buf).
+		Get(nil)
+	if err == nil || buf.Len() > 0 || atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("expected error without request, got %v after %d requests (%d bytes written)", err, requests, buf.Len())
+	}
+}
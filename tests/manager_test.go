@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadManagerQueue(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	manager := beclient.NewDownloadManager(2)
+	var jobs []*beclient.DownloadJob
+	for i := 0; i < 5; i++ {
+		jobs = append(jobs, manager.Add(srv.URL, filepath.Join(dir, fmt.Sprintf("data%d.bin", i))))
+	}
+	for _, job := range jobs {
+		if err := job.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status() != beclient.DownloadJobCompleted {
+			t.Fatalf("status %s, want completed", job.Status())
+		}
+		if progress := job.Progress(); progress == nil || progress.DoneSize != int64(len(data)) {
+			t.Fatalf("unexpected progress: %+v", progress)
+		}
+		got, _ := ioutil.ReadFile(job.SavePath())
+		if !bytes.Equal(got, data) {
+			t.Fatal("downloaded content mismatch")
+		}
+	}
+}
+
+func TestDownloadManagerPauseResumeCancel(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	manager := beclient.NewDownloadManager(2).RateLimit(128 * 1024)
+	job := manager.Add(srv.URL, filepath.Join(dir, "data.bin"))
+	other := manager.Add(srv.URL, filepath.Join(dir, "other.bin"))
+
+	// 暂停后保留已下载的内容
+	time.Sleep(time.Millisecond * 300)
+	job.Pause()
+	other.Cancel()
+	if job.Status() != beclient.DownloadJobPaused {
+		t.Fatalf("status %s, want paused", job.Status())
+	}
+	if err := other.Wait(); !errors.Is(err, beclient.ErrDownloadCanceled) {
+		t.Fatalf("cancel error %v", err)
+	}
+
+	// 恢复后继续下载
+	time.Sleep(time.Millisecond * 100)
+	job.Resume()
+	if err := job.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(job.SavePath())
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	manager.Stop()
+	// 取消的任务不保留文件
+	if _, err := os.Stat(other.SavePath() + ".part"); !os.IsNotExist(err) {
+		t.Fatalf("partial file of canceled job exists: %v", err)
+	}
+}
+
+func TestDownloadManagerPersist(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	queuePath := filepath.Join(dir, "queue.json")
+	savePath := filepath.Join(dir, "data.bin")
+
+	// 下载中途停止
+	manager := beclient.NewDownloadManager(1).RateLimit(128 * 1024)
+	if _, err := manager.Persist(queuePath); err != nil {
+		t.Fatal(err)
+	}
+	manager.Add(srv.URL, savePath)
+	time.Sleep(time.Millisecond * 300)
+	manager.Stop()
+
+	// 重新加载后继续下载
+	manager = beclient.NewDownloadManager(1)
+	jobs, err := manager.Persist(queuePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].SavePath() != savePath {
+		t.Fatalf("unexpected jobs: %v", jobs)
+	}
+	if err = jobs[0].Wait(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	content, _ := ioutil.ReadFile(queuePath)
+	if string(content) != "[]" {
+		t.Fatalf("queue not cleared: %s", content)
+	}
+}
+
+func TestDownloadManagerRequestFactory(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	manager := beclient.NewDownloadManager(1)
+	var events int32
+	// 创建请求的方法可以调用管理器，配置的进度事件回调不会被替换
+	manager.Request(func(url string) *beclient.BeClient {
+		_ = manager.Jobs()
+		return beclient.New(url).DownloadEvents(0, func(progress *beclient.DownloadProgress) {
+			atomic.AddInt32(&events, 1)
+		})
+	})
+	job := manager.Add(srv.URL, filepath.Join(dir, "data.bin"))
+	queued := manager.Add(srv.URL, filepath.Join(dir, "queued.bin"))
+
+	// 取消排队中的任务不会死锁
+	done := make(chan struct{})
+	go func() {
+		queued.Cancel()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancel deadlocked")
+	}
+	if err := job.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&events) == 0 {
+		t.Fatal("user download events handler was replaced")
+	}
+	if job.Progress() == nil {
+		t.Fatal("job progress not recorded")
+	}
+	manager.Stop()
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Trace")))
+	}))
+	defer srv.Close()
+
+	// 记录中间件执行顺序
+	var order []string
+	trace := func(name string) beclient.Middleware {
+		return func(next beclient.RoundTripFunc) beclient.RoundTripFunc {
+			return func(request *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				request.Header.Set("X-Trace", request.Header.Get("X-Trace")+name)
+				return next(request)
+			}
+		}
+	}
+
+	client := beclient.NewClient(srv.URL).Use(trace("a"), trace("b"))
+	var res []byte
+	err := client.R().
+		Use(trace("c")).
+		Use(beclient.AfterResponse(func(response *http.Response, err error) (*http.Response, error) {
+			if err == nil {
+				response.Header.Set("X-After", "1")
+			}
+			return response, err
+		})).
+		Get(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "abc" || strings.Join(order, "") != "abc" {
+		t.Fatalf("unexpected middleware order: %q %q", res, order)
+	}
+
+	// 客户端中间件不受请求中间件影响
+	order = nil
+	if err = client.R().Get(&res); err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "ab" {
+		t.Fatalf("request middleware leaked into client: %q", res)
+	}
+}
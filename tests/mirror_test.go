@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadMirrors(t *testing.T) {
+	data := randomData(1024 * 1024)
+	var primaryGets, goodGets, brokenGets, staleGets int64
+	// 请求的地址
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&primaryGets, 1)
+		}
+		serveData(w, r, data)
+	}))
+	defer primary.Close()
+	// 正常的镜像
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&goodGets, 1)
+		}
+		serveData(w, r, data)
+	}))
+	defer good.Close()
+	// HEAD正常，下载时总是失败的镜像
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&brokenGets, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		serveData(w, r, data)
+	}))
+	defer broken.Close()
+	// ETag不一致的镜像
+	stale := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&staleGets, 1)
+		}
+		w.Header().Set("ETag", `"stale"`)
+		http.ServeContent(w, r, "data.bin", time.Unix(1600000000, 0), bytes.NewReader(data))
+	}))
+	defer stale.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(primary.URL).
+		DownloadMultiThread(4, 1024*64).
+		Download(savePath, nil).
+		DownloadBufferSize(1024*16).
+		DownloadMirrors(good.URL, broken.URL, stale.URL).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	if primaryGets == 0 || goodGets == 0 {
+		t.Fatalf("segments not spread across mirrors: primary=%d good=%d", primaryGets, goodGets)
+	}
+	// 503响应后立即停止使用，仅停止前已发出的请求会到达
+	if brokenGets == 0 || brokenGets > 4 {
+		t.Fatalf("broken mirror requested %d times", brokenGets)
+	}
+	if staleGets != 0 {
+		t.Fatalf("stale mirror requested %d times, want 0", staleGets)
+	}
+}
+
+func TestDownloadMirrorsAttempts(t *testing.T) {
+	data := randomData(1024 * 1024)
+	var firstGets int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不复用连接，避免Transport自动重发断开的请求
+		w.Header().Set("Connection", "close")
+		// 第一个分段在全部镜像上都会断开连接，其他分段正常
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") && r.Header.Get("Range") != "bytes=0-0" {
+			atomic.AddInt32(&firstGets, 1)
+			panic(http.ErrAbortHandler)
+		}
+		serveData(w, r, data)
+	})
+	primary := httptest.NewServer(handler)
+	defer primary.Close()
+	mirror := httptest.NewServer(handler)
+	defer mirror.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := beclient.New(primary.URL).
+		WithContext(ctx).
+		Retry(beclient.RetryPolicy{MaxAttempts: 4, MinBackoff: time.Millisecond}).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024*16).
+		DownloadMirrors(mirror.URL).
+		Download(filepath.Join(t.TempDir(), "data.bin"), nil).
+		Get(nil)
+	if err == nil || ctx.Err() != nil {
+		t.Fatalf("expected segment failure before timeout, got %v", err)
+	}
+	// 镜像切换计入尝试次数
+	if got := atomic.LoadInt32(&firstGets); got != 4 {
+		t.Fatalf("first segment requested %d times, want 4", got)
+	}
+}
+
+func TestDownloadMirrorsRetryStatus(t *testing.T) {
+	data := randomData(1024 * 1024)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer primary.Close()
+	// 第一次下载请求返回503，之后正常的镜像
+	var mirrorGets, mirrorServed int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("Range") != "bytes=0-0" {
+			if atomic.AddInt32(&mirrorGets, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			atomic.AddInt32(&mirrorServed, 1)
+		}
+		serveData(w, r, data)
+	}))
+	defer mirror.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(primary.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024*16).
+		DownloadMirrors(mirror.URL).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	// 可重试的状态码不会立即停止使用镜像
+	if served := atomic.LoadInt32(&mirrorServed); served < 3 {
+		t.Fatalf("mirror served %d segments after a single 503", served)
+	}
+}
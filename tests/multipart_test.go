@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestMultipartForm(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1024 * 1024); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		content, _ := ioutil.ReadAll(file)
+		w.Write([]byte(strings.Join([]string{
+			r.FormValue("name"),
+			r.FormValue("desc"),
+			header.Filename,
+			header.Header.Get("Content-Type"),
+			string(content),
+		}, "|")))
+	}))
+	defer srv.Close()
+
+	var res []byte
+	err := beclient.New(srv.URL).
+		Path("/upload").
+		Body(map[string]string{"name": "bearki"}).
+		ContentType(beclient.ContentTypeFormBody).
+		// 自定义的Content-Type不能丢失分隔符
+		Header("Content-Type", "multipart/form-data").
+		FormField("desc", "hello").
+		FormFile("file", "a.txt", strings.NewReader("file content"), textproto.MIMEHeader{
+			"Content-Type": {"text/plain"},
+		}).
+		Post(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "bearki|hello|a.txt|text/plain|file content" {
+		t.Fatalf("unexpected response: %s", res)
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadDirFileName(t *testing.T) {
+	data := randomData(16 * 1024)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rfc5987", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="fallback.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt`)
+		serveData(w, r, data)
+	})
+	mux.HandleFunc("/traversal", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="../../evil.sh"`)
+		serveData(w, r, data)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/files/report.pdf?token=1", http.StatusFound)
+	})
+	mux.HandleFunc("/files/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cases := map[string]string{
+		"/rfc5987":   "报告.txt",
+		"/traversal": "evil.sh",
+		"/redirect":  "report.pdf",
+		"/":          "download.json",
+	}
+	for path, want := range cases {
+		client := beclient.New(srv.URL+path).DownloadDir(dir, nil)
+		if err := client.Get(nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := client.GetSavePath(); got != filepath.Join(dir, want) {
+			t.Fatalf("%s: save path %s, want %s", path, got, filepath.Join(dir, want))
+		}
+		content, _ := ioutil.ReadFile(filepath.Join(dir, want))
+		if !bytes.Equal(content, data) {
+			t.Fatalf("%s: downloaded content mismatch", path)
+		}
+	}
+}
+
+func TestDownloadConflict(t *testing.T) {
+	data := randomData(16 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "data.bin")
+	if err := ioutil.WriteFile(existing, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// 跳过已存在的文件
+	client := beclient.New(srv.URL+"/data.bin").DownloadDir(dir, nil).DownloadConflict(beclient.ConflictSkip)
+	if err := client.Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(existing); string(content) != "old" {
+		t.Fatal("existing file should be kept")
+	}
+
+	// 自动重命名
+	for i, want := range []string{"data (1).bin", "data (2).bin"} {
+		client = beclient.New(srv.URL+"/data.bin").DownloadDir(dir, nil).DownloadConflict(beclient.ConflictRename)
+		if err := client.Get(nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := client.GetSavePath(); got != filepath.Join(dir, want) {
+			t.Fatalf("rename %d: save path %s, want %s", i, got, filepath.Join(dir, want))
+		}
+	}
+
+	// 默认覆盖
+	if err := beclient.New(srv.URL).Download(existing, nil).Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := ioutil.ReadFile(existing); !bytes.Equal(content, data) {
+		t.Fatal("existing file should be overwritten")
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadEvents(t *testing.T) {
+	data := randomData(512 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	var mutex sync.Mutex
+	var events []*beclient.DownloadProgress
+	var legacyCalls int
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		Download(filepath.Join(t.TempDir(), "data.bin"), func(currSize, totalSize float64) {
+			legacyCalls++
+		}).
+		DownloadEvents(time.Millisecond*10, func(progress *beclient.DownloadProgress) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			events = append(events, progress)
+		}).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(events) < 2 || events[0].Event != beclient.DownloadEventStart {
+		t.Fatalf("unexpected first event: %+v", events)
+	}
+	last := events[len(events)-1]
+	if last.Event != beclient.DownloadEventFinish || last.Err != nil {
+		t.Fatalf("unexpected last event: %+v", last)
+	}
+	if last.DoneSize != int64(len(data)) || last.TotalSize != int64(len(data)) {
+		t.Fatalf("final size %d/%d, want %d", last.DoneSize, last.TotalSize, len(data))
+	}
+	if len(last.Segments) < 2 || last.FinishedSegments != len(last.Segments) {
+		t.Fatalf("finished segments %d/%d", last.FinishedSegments, len(last.Segments))
+	}
+	completed := 0
+	for _, event := range events {
+		if event.Event == beclient.DownloadEventSegmentComplete {
+			if event.Segment == nil || event.Segment.State != "finished" {
+				t.Fatalf("segment event without finished segment: %+v", event)
+			}
+			completed++
+		}
+	}
+	if completed != len(last.Segments) {
+		t.Fatalf("segment complete events %d, want %d", completed, len(last.Segments))
+	}
+	if legacyCalls == 0 {
+		t.Fatal("download callback not called")
+	}
+}
+
+func TestDownloadCallbackPerRead(t *testing.T) {
+	data := randomData(64 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	// 未配置DownloadEvents时每次读取后回调
+	var calls int
+	var lastCurr, lastTotal float64
+	err := beclient.New(srv.URL).
+		DownloadBufferSize(1024).
+		Download(filepath.Join(t.TempDir(), "data.bin"), func(currSize, totalSize float64) {
+			calls++
+			lastCurr, lastTotal = currSize, totalSize
+		}).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls < len(data)/1024 {
+		t.Fatalf("download callback called %d times, want at least %d", calls, len(data)/1024)
+	}
+	if lastCurr != float64(len(data)) || lastTotal != float64(len(data)) {
+		t.Fatalf("unexpected final progress %v/%v", lastCurr, lastTotal)
+	}
+}
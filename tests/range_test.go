@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestRangeIgnoredFallback(t *testing.T) {
+	data := randomData(512 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 声明支持Range，但总是返回完整内容
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", "524288")
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+}
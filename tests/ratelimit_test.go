@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestRateLimitDownload(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	// 令牌桶初始为1秒的流量，剩余部分至少需要1秒
+	start := time.Now()
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*32).
+		Download(filepath.Join(t.TempDir(), "data.bin"), nil).
+		RateLimit(128 * 1024).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*900 {
+		t.Fatalf("download finished in %v, rate limit not applied", elapsed)
+	}
+}
+
+func TestRateLimitUploadShared(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received += len(body)
+	}))
+	defer srv.Close()
+
+	// 两个客户端共享同一限速器
+	limiter := beclient.NewRateLimiter(128 * 1024)
+	client1 := beclient.NewClient(srv.URL).Limiter(limiter)
+	client2 := beclient.NewClient(srv.URL).Limiter(limiter)
+	data := bytes.Repeat([]byte("a"), 128*1024)
+
+	start := time.Now()
+	if err := client1.R().ContentType(beclient.ContentTypeJson).Body(data).Post(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client2.R().ContentType(beclient.ContentTypeJson).Body(data).Post(nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*900 {
+		t.Fatalf("upload finished in %v, rate limit not applied", elapsed)
+	}
+	if received < 2*128*1024 {
+		t.Fatalf("received %d bytes", received)
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestMultiThreadResume(t *testing.T) {
+	data := randomData(1024 * 1024)
+	var failing int32 = 1
+	var served int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end int64
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		// 第一次下载时后半部分分段在其他分段完成后失败
+		if atomic.LoadInt32(&failing) == 1 && start >= int64(len(data)/2) {
+			time.Sleep(time.Millisecond * 300)
+			http.Error(w, "unavailable", http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodGet {
+			atomic.AddInt64(&served, end-start+1)
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	download := func() error {
+		return beclient.New(srv.URL).
+			DownloadMultiThread(4, 1024*256).
+			DownloadBufferSize(1024).
+			DownloadResume().
+			Download(savePath, nil).
+			Get(nil)
+	}
+	if err := download(); err == nil {
+		t.Fatal("expected first download to fail")
+	}
+	if _, err := os.Stat(savePath + ".beclient"); err != nil {
+		t.Fatalf("resume state not saved: %v", err)
+	}
+
+	// 第二次下载只需要下载缺失的部分
+	atomic.StoreInt32(&failing, 0)
+	atomic.StoreInt64(&served, 0)
+	if err := download(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	if served := atomic.LoadInt64(&served); served > int64(len(data)/2) {
+		t.Fatalf("expected only missing ranges to be downloaded, served %d bytes", served)
+	}
+	if _, err := os.Stat(savePath + ".beclient"); !os.IsNotExist(err) {
+		t.Fatal("resume state should be removed after success")
+	}
+}
+
+func TestSingleThreadResume(t *testing.T) {
+	data := randomData(64 * 1024)
+	var broken int32 = 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不支持HEAD，资源小于下载缓冲区，走单线程下载
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// Range探测请求正常响应
+		if r.Header.Get("Range") == "bytes=0-0" {
+			serveData(w, r, data)
+			return
+		}
+		// 第一次下载时传输一半后断开连接
+		if atomic.CompareAndSwapInt32(&broken, 1, 0) {
+			w.Header().Set("ETag", `"beclient-test"`)
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		if len(r.Header.Get("Range")) == 0 {
+			t.Error("expected resumed request to carry Range")
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	download := func() error {
+		return beclient.New(srv.URL).
+			DownloadResume().
+			Download(savePath, nil).
+			Get(nil)
+	}
+	if err := download(); err == nil {
+		t.Fatal("expected first download to fail")
+	}
+	if err := download(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+}
+
+func TestResumeCompleteVerify(t *testing.T) {
+	data := randomData(64 * 1024)
+	var broken int32 = 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// 第一次下载时传输一半后断开连接
+		if r.Header.Get("Range") != "bytes=0-0" && atomic.CompareAndSwapInt32(&broken, 1, 0) {
+			w.Header().Set("ETag", `"beclient-test"`)
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(data)
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	download := func() error {
+		return beclient.New(srv.URL).
+			DownloadResume().
+			DownloadVerify(beclient.HashSHA256, hex.EncodeToString(sum[:])).
+			Download(savePath, nil).
+			Get(nil)
+	}
+	if err := download(); err == nil {
+		t.Fatal("expected first download to fail")
+	}
+	// 未完成的文件被损坏为完整大小，续传时服务端响应416
+	corrupt := make([]byte, len(data))
+	if err := ioutil.WriteFile(savePath+".part", corrupt, 0666); err != nil {
+		t.Fatal(err)
+	}
+	var checksumErr *beclient.ChecksumError
+	if err := download(); !errors.As(err, &checksumErr) {
+		t.Fatalf("expected *ChecksumError, got %v", err)
+	}
+	if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+		t.Fatal("corrupt file should not be moved to the save path")
+	}
+}
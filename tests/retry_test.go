@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestRetry(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var res []byte
+	err := beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}).
+		Get(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != "ok" || atomic.LoadInt32(&count) != 3 {
+		t.Fatalf("unexpected result %q after %d attempts", res, count)
+	}
+
+	// 非幂等请求默认不重试
+	atomic.StoreInt32(&count, 0)
+	err = beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}).
+		Post(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&count) != 1 {
+		t.Fatalf("POST should not be retried, got %d attempts", count)
+	}
+}
+
+func TestRetrySegmentAttempts(t *testing.T) {
+	data := randomData(512 * 1024)
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 不复用连接，避免Transport自动重发断开的请求
+		w.Header().Set("Connection", "close")
+		// 第一个分段的请求总是断开连接
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") && r.Header.Get("Range") != "bytes=0-0" {
+			atomic.AddInt32(&count, 1)
+			panic(http.ErrAbortHandler)
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	err := beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		Download(filepath.Join(t.TempDir(), "data.bin"), nil).
+		Get(nil)
+	if err == nil {
+		t.Fatal("expected download error")
+	}
+	// 分段只在一层重试，总请求数不超过最大尝试次数
+	if got := atomic.LoadInt32(&count); got != 3 {
+		t.Fatalf("first segment requested %d times, want 3", got)
+	}
+}
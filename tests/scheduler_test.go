@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+// slowWriter 每次写入前等待，模拟慢速连接
+type slowWriter struct {
+	http.ResponseWriter
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.ResponseWriter.Write(p)
+}
+
+func TestDownloadWorkStealing(t *testing.T) {
+	data := randomData(2 * 1024 * 1024)
+	var mutex sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader := r.Header.Get("Range")
+		mutex.Lock()
+		ranges = append(ranges, rangeHeader)
+		mutex.Unlock()
+		// 第一个分段的连接很慢
+		if strings.HasPrefix(rangeHeader, "bytes=0-") {
+			w = &slowWriter{ResponseWriter: w, delay: time.Millisecond * 100}
+		}
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(2, 1024*1024).
+		DownloadBufferSize(1024*16).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+
+	// 空闲线程应拆分慢速分段的剩余部分
+	mutex.Lock()
+	defer mutex.Unlock()
+	stolen := false
+	for _, val := range ranges {
+		var start, end int64
+		if _, err := fmt.Sscanf(val, "bytes=%d-%d", &start, &end); err == nil && start > 0 && start < 256*1024 {
+			stolen = true
+		}
+	}
+	if !stolen {
+		t.Fatalf("slow segment was not split: %v", ranges)
+	}
+}
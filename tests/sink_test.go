@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadTo(t *testing.T) {
+	data := randomData(128 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	if err := beclient.New(srv.URL).DownloadTo(&buf).Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("downloaded content mismatch")
+	}
+}
+
+func TestDownloadToWriterAt(t *testing.T) {
+	data := randomData(512 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "data.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	err = beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		DownloadToWriterAt(file, int64(len(data))).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(data))
+	if _, err = file.ReadAt(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+
+	// 期望大小不一致
+	err = beclient.New(srv.URL).DownloadToWriterAt(file, 1).Get(nil)
+	if err == nil {
+		t.Fatal("expected size mismatch error")
+	}
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+func TestEventStream(t *testing.T) {
+	var count int32
+	var lastID atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		if atomic.AddInt32(&count, 1) == 1 {
+			// 第一次连接发送事件后断开
+			w.Write([]byte("retry: 10\n\n: comment\nid: 1\nevent: greet\ndata: hello\ndata:world\n\ndata: partial"))
+			return
+		}
+		lastID.Store(r.Header.Get("Last-Event-ID"))
+		w.Write([]byte("data: two\r\n\r\nid: 3\rdata: three\r\r"))
+		w.(http.Flusher).Flush()
+		// 保持连接直到客户端停止接收
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	var events []*beclient.SSEEvent
+	err := beclient.New(srv.URL).EventStream(func(event *beclient.SSEEvent) error {
+		events = append(events, event)
+		if event.Data == "three" {
+			return beclient.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || atomic.LoadInt32(&count) != 2 {
+		t.Fatalf("unexpected %d events after %d connections", len(events), count)
+	}
+	if e := events[0]; e.ID != "1" || e.Event != "greet" || e.Data != "hello\nworld" {
+		t.Fatalf("unexpected first event %+v", e)
+	}
+	if e := events[1]; e.ID != "1" || e.Event != "message" || e.Data != "two" {
+		t.Fatalf("unexpected second event %+v", e)
+	}
+	if e := events[2]; e.ID != "3" || e.Data != "three" {
+		t.Fatalf("unexpected third event %+v", e)
+	}
+	if lastID.Load() != "1" {
+		t.Fatalf("unexpected Last-Event-ID %v", lastID.Load())
+	}
+}
+
+func TestEventStreamStop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/done":
+			w.WriteHeader(http.StatusNoContent)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	handler := func(event *beclient.SSEEvent) error { return nil }
+	// 204停止重连
+	if err := beclient.New(srv.URL).Path("/done").EventStream(handler); err != nil {
+		t.Fatal(err)
+	}
+	// 非2xx响应返回*StatusError
+	var statusErr *beclient.StatusError
+	if err := beclient.New(srv.URL).Path("/missing").EventStream(handler); !errors.As(err, &statusErr) {
+		t.Fatalf("expected status error, got %v", err)
+	}
+	// 上下文结束时停止
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := beclient.New(srv.URL).WithContext(ctx).EventStream(handler); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestEventStreamResume(t *testing.T) {
+	var count int32
+	var lastID atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if atomic.AddInt32(&count, 1) == 1 {
+			// 第二个事件未接收完整时断开
+			w.Write([]byte("retry: 10\nid: 1\ndata: one\n\nid: 2\ndata: partial"))
+			return
+		}
+		lastID.Store(r.Header.Get("Last-Event-ID"))
+		// 推送时长超过TimeOut
+		w.(http.Flusher).Flush()
+		for _, data := range []string{"two", "three"} {
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("data: " + data + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	var events []string
+	err := beclient.New(srv.URL).TimeOut(100 * time.Millisecond).EventStream(func(event *beclient.SSEEvent) error {
+		events = append(events, event.ID+":"+event.Data)
+		if event.Data == "three" {
+			return beclient.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(events, ",") != "1:one,1:two,1:three" || atomic.LoadInt32(&count) != 2 {
+		t.Fatalf("unexpected events %v after %d connections", events, count)
+	}
+	// 未分发的事件ID不能作为Last-Event-ID
+	if lastID.Load() != "1" {
+		t.Fatalf("unexpected Last-Event-ID %v", lastID.Load())
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+// noHeadServer 拒绝HEAD请求并且不声明Accept-Ranges，但支持Range的服务端
+func noHeadServer(data []byte, rangeGets *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=") {
+			atomic.AddInt64(rangeGets, 1)
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		serveData(&noAcceptRanges{w}, r, data)
+	}))
+}
+
+// noAcceptRanges 删除Accept-Ranges响应头
+type noAcceptRanges struct {
+	http.ResponseWriter
+}
+
+func (w *noAcceptRanges) WriteHeader(statusCode int) {
+	w.Header().Del("Accept-Ranges")
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func TestStatRangeProbe(t *testing.T) {
+	data := randomData(128 * 1024)
+	var rangeGets int64
+	srv := noHeadServer(data, &rangeGets)
+	defer srv.Close()
+
+	stat, err := beclient.New(srv.URL).Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size != int64(len(data)) || !stat.AcceptRanges {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+	if stat.ETag != `"beclient-test"` || stat.LastModified == "" || stat.ContentType != "application/octet-stream" {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+}
+
+func TestDownloadRangeProbe(t *testing.T) {
+	data := randomData(512 * 1024)
+	var rangeGets int64
+	srv := noHeadServer(data, &rangeGets)
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	err := beclient.New(srv.URL).
+		DownloadMultiThread(4, 1024*64).
+		DownloadBufferSize(1024).
+		Download(savePath, nil).
+		Get(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(savePath)
+	if !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	// 探测请求及多个分段请求
+	if rangeGets < 3 {
+		t.Fatalf("range requests %d, multi-thread download not used", rangeGets)
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+type streamItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// streamServer 按请求路径返回JSON数组或NDJSON
+func streamServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/array":
+			w.Write([]byte(`[{"id":1,"name":"a"}, {"id":2,"name":"b"}, {"id":3,"name":"c"}]`))
+		case "/ndjson":
+			w.Write([]byte("{\"id\":1,\"name\":\"a\"}\n\n{\"id\":2,\"name\":\"b\"}\r\n{\"id\":3,\"name\":\"c\"}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+}
+
+func TestStream(t *testing.T) {
+	srv := streamServer()
+	defer srv.Close()
+
+	var content string
+	err := beclient.New(srv.URL).Path("/array").Stream(func(r io.Reader) error {
+		body, err := ioutil.ReadAll(r)
+		content = string(body)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "[{") {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	// Do返回未读取的响应
+	res, err := beclient.New(srv.URL).Path("/ndjson").Method(beclient.MethodPost).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasPrefix(string(body), `{"id":1`) {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// 开启StatusCheck时非2xx响应返回*StatusError
+	_, err = beclient.New(srv.URL).Path("/missing").StatusCheck().Do()
+	var statusErr *beclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestStreamDecode(t *testing.T) {
+	srv := streamServer()
+	defer srv.Close()
+
+	for _, path := range []string{"/array", "/ndjson"} {
+		var items []streamItem
+		collect := func(item streamItem) error {
+			items = append(items, item)
+			return nil
+		}
+		r := beclient.New(srv.URL).Path(path)
+		var err error
+		if path == "/array" {
+			err = r.JSONArray(collect)
+		} else {
+			err = r.NDJSON(collect)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if len(items) != 3 || items[2].ID != 3 || items[2].Name != "c" {
+			t.Fatalf("%s: unexpected items %+v", path, items)
+		}
+	}
+
+	// 指针参数及提前停止
+	count := 0
+	err := beclient.DecodeJSONArray(strings.NewReader(`[{"id":1},{"id":2},{"id":3}]`), func(item *streamItem) error {
+		if count++; item.ID == 2 {
+			return beclient.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("unexpected stop after %d items: %v", count, err)
+	}
+
+	// 错误的内容
+	if err = beclient.DecodeJSONArray(strings.NewReader(`{"id":1}`), func(streamItem) error { return nil }); err == nil {
+		t.Fatal("expected error for non-array content")
+	}
+	err = beclient.DecodeNDJSON(strings.NewReader("{\"id\":1}\n{bad}\n"), func(streamItem) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected line error, got %v", err)
+	}
+	if err = beclient.DecodeNDJSON(strings.NewReader(""), func(int) {}); err == nil {
+		t.Fatal("expected invalid callback error")
+	}
+}
+
+func TestStreamTimeOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		// 持续推送超过TimeOut的时长
+		case "/slow":
+			for i := 1; i <= 6; i++ {
+				fmt.Fprintf(w, "{\"id\":%d}\n", i)
+				w.(http.Flusher).Flush()
+				time.Sleep(100 * time.Millisecond)
+			}
+		// 响应头超过TimeOut才返回
+		case "/hang":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	// TimeOut不限制读取响应体的时长
+	var count int
+	err := beclient.New(srv.URL).Path("/slow").TimeOut(300 * time.Millisecond).NDJSON(func(item streamItem) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Fatalf("expected 6 items, got %d", count)
+	}
+
+	// TimeOut限制等待响应头的时长
+	_, err = beclient.New(srv.URL).Path("/hang").TimeOut(100 * time.Millisecond).Do()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
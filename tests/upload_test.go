@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bearki/beclient"
+)
+
+// uploadServer 返回请求体大小及内容，前failures次请求响应503
+func uploadServer(failures int32) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Content-Length", strings.Join(r.Header["Content-Length"], ","))
+		w.Write(body)
+	}))
+	return srv, &count
+}
+
+func TestUploadFile(t *testing.T) {
+	srv, count := uploadServer(1)
+	defer srv.Close()
+
+	content := strings.Repeat("beclient", 4096)
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var currSize, totalSize float64
+	var res string
+	r := beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, StatusCodes: []int{http.StatusServiceUnavailable}}).
+		Body(file).
+		UploadProgress(func(curr, total float64) {
+			currSize, totalSize = curr, total
+		})
+	if err = r.Put(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res != content || atomic.LoadInt32(count) != 2 {
+		t.Fatalf("unexpected response of %d bytes after %d attempts", len(res), *count)
+	}
+	response, _ := r.GetResponse()
+	if got := response.Header.Get("X-Content-Length"); got != "32768" {
+		t.Fatalf("unexpected Content-Length %q", got)
+	}
+	if currSize != float64(len(content)) || totalSize != float64(len(content)) {
+		t.Fatalf("unexpected progress %v/%v", currSize, totalSize)
+	}
+}
+
+func TestUploadFactory(t *testing.T) {
+	srv, _ := uploadServer(1)
+	defer srv.Close()
+
+	var opened int32
+	factory := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&opened, 1)
+		return ioutil.NopCloser(strings.NewReader("factory")), nil
+	}
+	var res string
+	err := beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, StatusCodes: []int{http.StatusServiceUnavailable}}).
+		Body(factory).
+		Put(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "factory" || atomic.LoadInt32(&opened) != 2 {
+		t.Fatalf("unexpected response %q after opening %d bodies", res, opened)
+	}
+}
+
+func TestUploadReader(t *testing.T) {
+	srv, count := uploadServer(1)
+	defer srv.Close()
+
+	// 不可Seek的读取器大小未知且不会重试
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte("stream"))
+		writer.Close()
+	}()
+	var totalSize float64
+	r := beclient.New(srv.URL).
+		Retry(beclient.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, StatusCodes: []int{http.StatusServiceUnavailable}}).
+		Body(reader).
+		UploadProgress(func(curr, total float64) {
+			totalSize = total
+		})
+	if err := r.Put(nil); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(count) != 1 || totalSize != -1 {
+		t.Fatalf("unexpected %d attempts with total size %v", *count, totalSize)
+	}
+	response, _ := r.GetResponse()
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+}
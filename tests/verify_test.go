@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bearki/beclient"
+)
+
+func TestDownloadVerify(t *testing.T) {
+	data := randomData(256 * 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveData(w, r, data)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(data)
+	dir := t.TempDir()
+	download := func(savePath, expectedHex string) error {
+		return beclient.New(srv.URL).
+			DownloadMultiThread(4, 1024*64).
+			DownloadBufferSize(1024).
+			DownloadVerify(beclient.HashSHA256, expectedHex).
+			Download(savePath, nil).
+			Get(nil)
+	}
+	if err := download(filepath.Join(dir, "ok.bin"), hex.EncodeToString(sum[:])); err != nil {
+		t.Fatal(err)
+	}
+
+	var checksumErr *beclient.ChecksumError
+	savePath := filepath.Join(dir, "corrupt.bin")
+	if err := download(savePath, hex.EncodeToString(make([]byte, sha256.Size))); !errors.As(err, &checksumErr) {
+		t.Fatalf("expected *ChecksumError, got %v", err)
+	}
+	if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+		t.Fatal("corrupt file should not be moved to the save path")
+	}
+	if _, err := os.Stat(savePath + ".part"); !os.IsNotExist(err) {
+		t.Fatal("corrupt temp file should be removed")
+	}
+}
+
+func TestDownloadContentMD5(t *testing.T) {
+	data := randomData(16 * 1024)
+	sum := md5.Sum(data)
+	var corrupt bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		if corrupt {
+			w.Write(make([]byte, len(data)))
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	if err := beclient.New(srv.URL).Download(savePath, nil).Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	corrupt = true
+	var checksumErr *beclient.ChecksumError
+	if err := beclient.New(srv.URL).Download(savePath, nil).Get(nil); !errors.As(err, &checksumErr) {
+		t.Fatalf("expected *ChecksumError, got %v", err)
+	}
+	if checksumErr.Source != "Content-MD5" {
+		t.Fatalf("unexpected checksum source %q", checksumErr.Source)
+	}
+}
+
+func TestDownloadGzipDigest(t *testing.T) {
+	data := randomData(16 * 1024)
+	var encoded bytes.Buffer
+	gw := gzip.NewWriter(&encoded)
+	gw.Write(data)
+	gw.Close()
+	sum := sha256.Sum256(encoded.Bytes())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 校验值对应压缩后的内容
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		w.Write(encoded.Bytes())
+	}))
+	defer srv.Close()
+
+	// 自动解压时跳过响应头的校验值
+	savePath := filepath.Join(t.TempDir(), "data.bin")
+	if err := beclient.New(srv.URL).Download(savePath, nil).Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(savePath); !bytes.Equal(got, data) {
+		t.Fatal("downloaded content mismatch")
+	}
+	var buf bytes.Buffer
+	if err := beclient.New(srv.URL).DownloadTo(&buf).Get(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("streamed content mismatch")
+	}
+}